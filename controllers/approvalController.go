@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/middlewares"
+	"go-admin/models"
+	"go-admin/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// approvalRequired holds the actions that need a second user's approval before they are applied
+// Configured with APPROVAL_REQUIRED_ACTIONS as a comma-separated list of
+// "users:delete", "roles:delete" and "roles:update" (empty by default - every action applies immediately)
+var approvalRequired = approvalActions(util.Getenv("APPROVAL_REQUIRED_ACTIONS", ""))
+
+// approvalTTL is how long a change request may wait for review before it expires
+var approvalTTL = util.GetenvDuration("APPROVAL_TTL", 72*time.Hour)
+
+// changeAppliers apply an approved change request with the parameters of the original request
+// The result of the action is written to the reviewer's response
+var changeAppliers = map[string]func(c fiber.Ctx, request models.ChangeRequest) error{
+	models.ChangeDeleteUser: func(c fiber.Ctx, request models.ChangeRequest) error {
+		return deleteUser(c, request.TargetId, true)
+	},
+	models.ChangeDeleteRole: func(c fiber.Ctx, request models.ChangeRequest) error {
+		var params struct {
+			ReassignTo int `json:"reassign_to"`
+		}
+		if err := json.Unmarshal([]byte(request.Payload), &params); err != nil {
+			return err
+		}
+		return deleteRole(c, int(request.TargetId), params.ReassignTo, true)
+	},
+	models.ChangeUpdateRole: func(c fiber.Ctx, request models.ChangeRequest) error {
+		var role dto.Role
+		if err := json.Unmarshal([]byte(request.Payload), &role); err != nil {
+			return err
+		}
+		return updateRole(c, int(request.TargetId), role, true)
+	},
+}
+
+// approvalActions parses the comma-separated APPROVAL_REQUIRED_ACTIONS setting
+func approvalActions(list string) map[string]bool {
+	actions := map[string]bool{}
+	for _, action := range strings.Split(list, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions[action] = true
+		}
+	}
+	return actions
+}
+
+// ValidateApprovalActions checks that every action listed in APPROVAL_REQUIRED_ACTIONS can be held for approval
+// Called at startup, so a misspelled action fails loudly instead of silently applying immediately
+func ValidateApprovalActions() error {
+	var unknown []string
+	for action := range approvalRequired {
+		if _, ok := changeAppliers[action]; !ok {
+			unknown = append(unknown, action)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	slices.Sort(unknown)
+	return fmt.Errorf("unsupported actions %s in APPROVAL_REQUIRED_ACTIONS", strings.Join(unknown, ", "))
+}
+
+// requestApproval stores an action as a pending change request instead of applying it
+// Responds with 202 Accepted and the created change request
+// payload holds the parameters needed to apply the action later and is stored as JSON
+func requestApproval(c fiber.Ctx, action string, targetId uint, payload interface{}) error {
+	// Identify the requesting admin from the JWT token
+	requesterId := reviewer(c)
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request := models.ChangeRequest{
+		Action:      action,
+		TargetId:    targetId,
+		Payload:     string(encoded),
+		Status:      models.ChangePending,
+		RequestedBy: &requesterId,
+		ExpiresAt:   time.Now().Add(approvalTTL),
+	}
+	if err := database.DB.WithContext(c).Create(&request).Error; err != nil {
+		return err
+	}
+
+	c.Status(202)
+	return c.JSON(request)
+}
+
+// AllChangeRequests retrieves a paginated list of change requests, most recent first
+// Stale pending requests are marked expired first
+// Query parameters:
+//   - page: page number (defaults to 1 if not provided)
+//   - status: only return requests with this status (e.g., "pending")
+func AllChangeRequests(c fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+
+	if err := models.ExpireChangeRequests(database.DB.WithContext(c), time.Now()); err != nil {
+		return err
+	}
+
+	db := database.DB.WithContext(c).Session(&gorm.Session{})
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status).Session(&gorm.Session{})
+	}
+
+	result, err := models.Paginate(db, &models.ChangeRequest{}, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// GetChangeRequest retrieves a specific change request by ID
+// URL parameter: id (change request identifier)
+func GetChangeRequest(c fiber.Ctx) error {
+	request, found, err := findChangeRequest(c)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errChangeRequestNotFound
+	}
+
+	return c.JSON(request)
+}
+
+// ApproveChangeRequest applies a pending change request on behalf of a second user
+// The reviewer must not be the user who made the request, and must hold the permission of
+// the held action itself (e.g., users:delete) besides approvals:approve (403 otherwise)
+// Responds with the result of the applied action; if applying fails, the request
+// stays pending so it can be approved again or rejected
+// URL parameter: id (change request identifier)
+func ApproveChangeRequest(c fiber.Ctx) error {
+	request, found, err := findChangeRequest(c)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errChangeRequestNotFound
+	}
+
+	reviewerId := reviewer(c)
+	if request.RequestedBy != nil && *request.RequestedBy == reviewerId {
+		return util.Forbidden("change requests must be approved by a different user")
+	}
+
+	apply, ok := changeAppliers[request.Action]
+	if !ok || request.Status != models.ChangePending {
+		return util.Conflict("change request is " + request.Status)
+	}
+
+	// Approving must not let a reviewer apply an action they could not perform themselves
+	resource, action, _ := strings.Cut(request.Action, ":")
+	if err := middlewares.IsAuthorized(c, resource, action); err != nil {
+		return err
+	}
+
+	// Claim the request so concurrent approvals cannot apply it twice
+	now := time.Now()
+	claim := database.DB.WithContext(c).Model(&models.ChangeRequest{}).
+		Where("id = ? AND status = ?", request.Id, models.ChangePending).
+		Updates(map[string]interface{}{"status": models.ChangeApproved, "reviewed_by": reviewerId, "reviewed_at": &now})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return util.Conflict("change request is no longer pending")
+	}
+
+	// Apply the action; release the claim if it did not succeed
+	err = apply(c, request)
+	if err != nil || c.Response().StatusCode() >= 400 {
+		release := database.DB.WithContext(c).Model(&models.ChangeRequest{}).
+			Where("id = ?", request.Id).
+			Updates(map[string]interface{}{"status": models.ChangePending, "reviewed_by": nil, "reviewed_at": nil})
+		if release.Error != nil {
+			// The request stays approved without having been applied - leave a trace for operators
+			util.Log(c).Error("failed to release change request claim", "change_request_id", request.Id, "error", release.Error)
+		}
+	}
+	return err
+}
+
+// RejectChangeRequest discards a pending change request without applying it
+// The requester may also reject (withdraw) their own request
+// Request body: { "reason": string } (optional)
+// URL parameter: id (change request identifier)
+func RejectChangeRequest(c fiber.Ctx) error {
+	var data dto.RejectChange
+
+	// Parse the optional JSON body
+	if len(c.Body()) > 0 {
+		if err := dto.Bind(c, &data); err != nil {
+			return err
+		}
+	}
+
+	request, found, err := findChangeRequest(c)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errChangeRequestNotFound
+	}
+
+	reviewerId := reviewer(c)
+	now := time.Now()
+	result := database.DB.WithContext(c).Model(&models.ChangeRequest{}).
+		Where("id = ? AND status = ?", request.Id, models.ChangePending).
+		Updates(map[string]interface{}{"status": models.ChangeRejected, "reason": data.Reason, "reviewed_by": reviewerId, "reviewed_at": &now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return util.Conflict("change request is " + request.Status)
+	}
+
+	request.Status = models.ChangeRejected
+	request.Reason = data.Reason
+	request.ReviewedBy = &reviewerId
+	request.ReviewedAt = &now
+	return c.JSON(request)
+}
+
+// findChangeRequest loads the change request named by the id URL parameter
+// Pending requests past their expiry are marked expired first
+func findChangeRequest(c fiber.Ctx) (models.ChangeRequest, bool, error) {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	if err := models.ExpireChangeRequests(database.DB.WithContext(c), time.Now()); err != nil {
+		return models.ChangeRequest{}, false, err
+	}
+
+	var request models.ChangeRequest
+	if err := database.DB.WithContext(c).Where("id = ?", id).Find(&request).Error; err != nil {
+		return models.ChangeRequest{}, false, err
+	}
+	return request, request.Id != 0, nil
+}
+
+// reviewer returns the ID of the authenticated user reviewing a change request
+func reviewer(c fiber.Ctx) uint {
+	id, _ := util.ParseJWT(c.Cookies("jwt"))
+	reviewerId, _ := strconv.Atoi(id)
+	return uint(reviewerId)
+}
+
+// errChangeRequestNotFound is returned for a missing change request
+var errChangeRequestNotFound = util.NotFound("change request not found")
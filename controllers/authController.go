@@ -1,49 +1,49 @@
 package controllers
 
 import (
+	"errors"
 	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/metrics"
+	"go-admin/middlewares"
 	"go-admin/models"
 	"go-admin/util"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
 // Register handles user registration
 // Validates password confirmation, creates a new user account with default role,
 // and returns the created user data (password excluded in response)
+// Fails with 409 if the email is already registered
 func Register(c fiber.Ctx) error {
-	var data map[string]string
+	var request dto.Register
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&data); err != nil {
+	// Parse and validate JSON request body (including password confirmation)
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Validate password confirmation matches
-	if data["password"] != data["password_confirm"] {
-		c.Status(400)
-		return c.JSON(fiber.Map{
-			"code":    400,
-			"message": "Passwords do not match",
-		})
-	}
-
 	// Create user instance with provided data
-	// RoleId 3 is assigned as the default role for new registrations
+	// Role 3 is assigned as the default role for new registrations
 	user := models.User{
-		FirstName: data["first_name"],
-		LastName:  data["last_name"],
-		Email:     data["email"],
-		RoleId:    3,
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+		RoleIds:   []uint{3},
 	}
+	user.AssignRoles()
 
 	// Hash password before storing (uses bcrypt internally)
-	user.SetPassword(data["password"])
+	user.SetPassword(request.Password)
 
-	// Persist user to database
-	database.DB.Create(&user)
+	// Persist user to database along with the user_roles assignment
+	if err := database.DB.WithContext(c).Omit("Roles.*").Create(&user).Error; err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
@@ -52,40 +52,43 @@ func Register(c fiber.Ctx) error {
 // Validates email and password, then issues a JWT token stored in an HTTP-only cookie
 // Returns success message on successful authentication
 func Login(c fiber.Ctx) error {
-	var data map[string]string
+	var request dto.Login
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&data); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
 	var user models.User
 
 	// Look up user by email address
-	database.DB.Where("email = ?", data["email"]).First(&user)
+	err := database.DB.WithContext(c).Where("email = ?", request.Email).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 
 	// Verify user exists (Id == 0 indicates no record found)
 	if user.Id == 0 {
-		c.Status(404)
-		return c.JSON(fiber.Map{
-			"code":    404,
-			"message": "email not found",
-		})
+		recordLogin(c, user, request.Email, "email_not_found")
+		return util.NotFound("email not found")
+	}
+
+	// Invited accounts cannot log in until the invitation has been accepted
+	if user.Pending {
+		recordLogin(c, user, request.Email, "invitation_pending")
+		return util.Forbidden("account has not been activated")
 	}
 
 	// Verify password against stored hash (uses bcrypt internally)
-	if err := user.ComparePassword(data["password"]); err != nil {
-		c.Status(400)
-		return c.JSON(fiber.Map{
-			"code":    400,
-			"message": "incorrect password",
-		})
+	if err := user.ComparePassword(request.Password); err != nil {
+		recordLogin(c, user, request.Email, "incorrect_password")
+		return util.BadRequest("incorrect password")
 	}
 
 	// Generate JWT token containing user ID
 	token, err := util.GenerateJWT(strconv.Itoa(int(user.Id)))
 	if err != nil {
-		return c.SendStatus(fiber.StatusInternalServerError)
+		return err
 	}
 
 	// Set JWT token in HTTP-only cookie (24 hour expiration)
@@ -98,13 +101,51 @@ func Login(c fiber.Ctx) error {
 	}
 	c.Cookie(&cookie)
 
+	// Record the successful attempt and update the user's last-login tracking
+	recordLogin(c, user, request.Email, "")
+
 	return c.JSON(fiber.Map{
 		"message": "success login",
 	})
 }
 
+// recordLogin stores a LoginEvent for a login attempt
+// An empty reason marks the attempt as successful, in which case the user's
+// LastLoginAt and LastLoginIp columns are updated as well
+// Failures are logged but do not fail the login itself
+func recordLogin(c fiber.Ctx, user models.User, email string, reason string) {
+	event := models.LoginEvent{
+		UserId:    user.Id,
+		Email:     email,
+		Success:   reason == "",
+		Reason:    reason,
+		Ip:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+	if err := database.DB.WithContext(c).Create(&event).Error; err != nil {
+		util.Log(c).Error("failed to record login event", "user_id", user.Id, "error", err)
+	}
+
+	if event.Success {
+		metrics.Logins.WithLabelValues("success").Inc()
+	} else {
+		metrics.Logins.WithLabelValues("failure").Inc()
+	}
+
+	if event.Success {
+		err := database.DB.WithContext(c).Model(&user).Updates(models.User{
+			LastLoginAt: &event.CreatedAt,
+			LastLoginIp: event.Ip,
+		}).Error
+		if err != nil {
+			util.Log(c).Error("failed to update last login", "user_id", user.Id, "error", err)
+		}
+	}
+}
+
 // User retrieves the current authenticated user's profile
 // Extracts user ID from JWT token in cookie and returns user data
+// The resolved permissions are embedded under "access" (see UserPermissions)
 // Password field is automatically excluded from response via JSON tag
 func User(c fiber.Ctx) error {
 	// Extract JWT token from authentication cookie
@@ -116,11 +157,36 @@ func User(c fiber.Ctx) error {
 	var user models.User
 
 	// Retrieve user record by ID
-	database.DB.Where("id = ?", id).First(&user)
+	if err := database.DB.WithContext(c).Where("id = ?", id).First(&user).Error; err != nil {
+		return lookupError(err, "user not found")
+	}
+
+	// Embed effective permissions so the frontend can build its menus
+	access, err := middlewares.EffectivePermissions(user.Id)
+	if err != nil {
+		return err
+	}
+	user.Access = &access
 
 	return c.JSON(user)
 }
 
+// UserPermissions retrieves the current authenticated user's effective permissions
+// Returns the resolved permission names and the allowed actions per resource,
+// computed with the same logic IsAuthorized uses
+func UserPermissions(c fiber.Ctx) error {
+	// Extract user ID from JWT token in authentication cookie
+	cookie := c.Cookies("jwt")
+	id, _ := util.ParseJWT(cookie)
+	userId, _ := strconv.Atoi(id)
+
+	access, err := middlewares.EffectivePermissions(uint(userId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(access)
+}
+
 // Logout invalidates the user session by clearing the JWT cookie
 // Sets cookie expiration to past time and empty value to force browser deletion
 func Logout(c fiber.Ctx) error {
@@ -141,11 +207,12 @@ func Logout(c fiber.Ctx) error {
 // UpdateInfo updates the authenticated user's personal information
 // Allows users to modify their first name, last name, and email
 // User ID is extracted from JWT token to ensure users can only update their own data
+// Fails with 409 if the email is already taken
 func UpdateInfo(c fiber.Ctx) error {
-	var data map[string]string
+	var request dto.UpdateInfo
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&data); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
@@ -157,13 +224,15 @@ func UpdateInfo(c fiber.Ctx) error {
 	// Prepare user instance with ID and updated fields
 	user := models.User{
 		Id:        uint(userId),
-		FirstName: data["first_name"],
-		LastName:  data["last_name"],
-		Email:     data["email"],
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
 	}
 
 	// Update user record in database
-	database.DB.Model(&user).Updates(user)
+	if err := database.DB.WithContext(c).Model(&user).Updates(user).Error; err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
@@ -172,22 +241,13 @@ func UpdateInfo(c fiber.Ctx) error {
 // Requires password confirmation to prevent typos
 // User ID is extracted from JWT token to ensure users can only change their own password
 func UpdatePassword(c fiber.Ctx) error {
-	var data map[string]string
+	var request dto.UpdatePassword
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&data); err != nil {
+	// Parse and validate JSON request body (including password confirmation)
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Validate password confirmation matches
-	if data["password"] != data["password_confirm"] {
-		c.Status(400)
-		return c.JSON(fiber.Map{
-			"code":    400,
-			"message": "Passwords do not match",
-		})
-	}
-
 	// Extract user ID from JWT token in authentication cookie
 	cookie := c.Cookies("jwt")
 	id, _ := util.ParseJWT(cookie)
@@ -199,10 +259,12 @@ func UpdatePassword(c fiber.Ctx) error {
 	}
 
 	// Hash new password before storing (uses bcrypt internally)
-	user.SetPassword(data["password"])
+	user.SetPassword(request.Password)
 
 	// Update password field in database
-	database.DB.Model(&user).Updates(user)
+	if err := database.DB.WithContext(c).Model(&user).Updates(user).Error; err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"errors"
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AllCategories retrieves the whole category tree
+// Root categories are returned with their descendants nested under children
+func AllCategories(c fiber.Ctx) error {
+	var categories []models.Category
+
+	// Ordering by path lists every parent before its children
+	if err := database.DB.WithContext(c).Order("path").Find(&categories).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(models.CategoryTree(categories))
+}
+
+// CreateCategory creates a new category, optionally below a parent category
+// Fails with 422 if the parent category does not exist or the category would be nested too deeply
+// Request body: see dto.Category (name, parent_id)
+func CreateCategory(c fiber.Ctx) error {
+	var request dto.Category
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	category := models.Category{
+		Name:     request.Name,
+		ParentId: categoryParentId(request),
+	}
+
+	// Persist category and its materialized path together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		parentPath := ""
+		if category.ParentId != nil {
+			// Lock the parent so it cannot be moved before the new path is written
+			var parent models.Category
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *category.ParentId).First(&parent).Error; err != nil {
+				return invalidCategoryParent(err)
+			}
+			parentPath = parent.Path
+		}
+
+		// The path ends with the category's own ID, which is only known after the insert
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		category.Path = models.ChildPath(parentPath, category.Id)
+		if len(category.Path) > models.MaxCategoryPath {
+			return invalidCategoryParent(models.ErrCategoryTooDeep)
+		}
+		if err := tx.Model(&category).Update("path", category.Path).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, c, models.AuditCreate, models.EntityCategories, category.Id, nil, category)
+	})
+	if err != nil {
+		return err
+	}
+	category.SetDepth()
+
+	return c.JSON(category)
+}
+
+// GetCategory retrieves a specific category with its descendants nested under children
+// Fails with 404 if the category does not exist
+// URL parameter: id (category identifier)
+func GetCategory(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	category, err := findCategory(c, id)
+	if err != nil {
+		return err
+	}
+
+	// Load the subtree by path prefix; the category itself comes first
+	var subtree []models.Category
+	if err := database.DB.WithContext(c).Where("path LIKE ?", category.Path+"%").Order("path").Find(&subtree).Error; err != nil {
+		return err
+	}
+
+	tree := models.CategoryTree(subtree)
+	if len(tree) == 0 {
+		return util.NotFound("category not found")
+	}
+	return c.JSON(tree[0])
+}
+
+// UpdateCategory renames a category and, when parent_id is sent, moves it with its
+// descendants below another category (or to the root level with null)
+// The category and its new parent are locked while the subtree is moved
+// Fails with 404 if the category does not exist and with 422 if the new parent does not
+// exist, is the category itself or one of its descendants, or would nest the subtree too deeply
+// Request body: see dto.Category (name, parent_id)
+// URL parameter: id (category identifier to update)
+func UpdateCategory(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var request dto.Category
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	var category models.Category
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Read the category under a row lock, so concurrent moves of it are serialized
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&category).Error; err != nil {
+			return lookupError(err, "category not found")
+		}
+		before := category
+
+		category.Name = request.Name
+		if err := tx.Model(&category).Update("name", category.Name).Error; err != nil {
+			return err
+		}
+
+		// Move the subtree when a parent was sent
+		if request.ParentId.Set {
+			if err := models.MoveCategory(tx, &category, categoryParentId(request)); err != nil {
+				return invalidCategoryParent(err)
+			}
+		}
+
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityCategories, category.Id, before, category)
+	})
+	if err != nil {
+		return err
+	}
+	category.SetDepth()
+
+	return c.JSON(category)
+}
+
+// DeleteCategory permanently removes a category
+// Products filed under it become uncategorized
+// Refuses with 409 Conflict while the category still has subcategories
+// Fails with 404 if the category does not exist
+// URL parameter: id (category identifier to delete)
+func DeleteCategory(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.Where("id = ?", id).First(&category).Error; err != nil {
+			return lookupError(err, "category not found")
+		}
+
+		var children int64
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return util.Conflict("category has " + strconv.FormatInt(children, 10) + " subcategories")
+		}
+
+		// Delete category (foreign keys detach its products)
+		if err := tx.Delete(&category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityCategories, category.Id, category, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "category deleted",
+	})
+}
+
+// findCategory loads the category named by id
+// Fails with 404 if it does not exist
+func findCategory(c fiber.Ctx, id int) (models.Category, error) {
+	var category models.Category
+	err := database.DB.WithContext(c).Where("id = ?", id).First(&category).Error
+	return category, lookupError(err, "category not found")
+}
+
+// categoryParentId returns the parent category ID of a category request
+// A missing parent_id, null or 0 means no parent
+func categoryParentId(request dto.Category) *uint {
+	if request.ParentId.Value == nil || *request.ParentId.Value == 0 {
+		return nil
+	}
+	parentId := uint(*request.ParentId.Value)
+	return &parentId
+}
+
+// invalidCategoryParent reports a rejected parent category as a 422 validation error
+// Errors other than a missing parent or a cycle are returned unchanged
+func invalidCategoryParent(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return util.Invalid("invalid category", fiber.Map{"parent_id": "parent category not found"})
+	case errors.Is(err, models.ErrCategoryCycle), errors.Is(err, models.ErrCategoryTooDeep):
+		return util.Invalid("invalid category", fiber.Map{"parent_id": err.Error()})
+	default:
+		return err
+	}
+}
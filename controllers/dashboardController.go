@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/middlewares"
+	"go-admin/models"
+	"go-admin/util"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// Dashboard defaults
+const (
+	defaultDashboardDays  = 30  // Period new users are counted over when days is not given
+	maxDashboardDays      = 366 // Longest period accepted, which also bounds the number of cache entries per caller
+	dashboardTopCustomers = 5   // Number of top customers returned
+)
+
+// dashboardKey identifies a cached dashboard
+// Row-level policies make the numbers depend on the caller, so entries are kept per user
+type dashboardKey struct {
+	userId uint
+	days   int
+}
+
+// dashboardEntry is a computed dashboard together with the time it stops being served
+type dashboardEntry struct {
+	dashboard models.Dashboard
+	expires   time.Time
+}
+
+// dashboardCache keeps computed dashboards in memory for DASHBOARD_CACHE_TTL (default 1m),
+// so a busy landing page does not rerun the aggregate queries on every load
+// Entries are not invalidated on writes; the numbers may lag behind by up to the TTL
+type dashboardCache struct {
+	mu      sync.Mutex
+	entries map[dashboardKey]dashboardEntry
+	ttl     time.Duration
+}
+
+// dashboards is the process-wide dashboard cache
+var dashboards = &dashboardCache{
+	entries: map[dashboardKey]dashboardEntry{},
+	ttl:     util.GetenvDuration("DASHBOARD_CACHE_TTL", time.Minute),
+}
+
+// get returns the cached dashboard for key, if it has not expired
+func (dc *dashboardCache) get(key dashboardKey, now time.Time) (models.Dashboard, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	entry, ok := dc.entries[key]
+	if !ok || now.After(entry.expires) {
+		return models.Dashboard{}, false
+	}
+	return entry.dashboard, true
+}
+
+// put caches a dashboard for key and drops expired entries
+func (dc *dashboardCache) put(key dashboardKey, dashboard models.Dashboard, now time.Time) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	for cached, entry := range dc.entries {
+		if now.After(entry.expires) {
+			delete(dc.entries, cached)
+		}
+	}
+	dc.entries[key] = dashboardEntry{dashboard: dashboard, expires: now.Add(dc.ttl)}
+}
+
+// Dashboard retrieves the headline numbers for the admin landing page:
+// total and new users, product and order counts, revenue, average order value and top customers
+// Users and orders are counted under the caller's row-level policies, so callers lacking
+// users:read or orders:read see zeros for them
+// Results are cached per caller and period for DASHBOARD_CACHE_TTL
+// Customer email addresses are masked unless the caller holds orders.email:read
+// Query parameter: days (period new users are counted over, 1 to 366, defaults to 30)
+func Dashboard(c fiber.Ctx) error {
+	days, err := strconv.Atoi(c.Query("days", strconv.Itoa(defaultDashboardDays)))
+	if err != nil || days <= 0 || days > maxDashboardDays {
+		return util.Invalid("invalid query", fiber.Map{"days": "must be a number from 1 to " + strconv.Itoa(maxDashboardDays)})
+	}
+
+	id, _ := util.ParseJWT(c.Cookies("jwt"))
+	userId, _ := strconv.Atoi(id)
+	key := dashboardKey{userId: uint(userId), days: days}
+
+	now := time.Now()
+	dashboard, ok := dashboards.get(key, now)
+	if !ok {
+		db := database.DB.WithContext(c)
+		sources := models.DashboardSources{
+			Users:    db.Scopes(middlewares.PolicyScope(c, models.ResourceUsers, models.ActionRead)).Session(&gorm.Session{}),
+			Products: db,
+			Orders:   db.Scopes(middlewares.PolicyScope(c, models.ResourceOrders, models.ActionRead)).Session(&gorm.Session{}),
+		}
+
+		// Count new users from the start of the first day of the period
+		year, month, day := now.AddDate(0, 0, -days).Date()
+		since := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+		dashboard, err = models.LoadDashboard(sources, since, dashboardTopCustomers)
+		if err != nil {
+			return err
+		}
+		dashboards.put(key, dashboard, now)
+	}
+
+	// Mask a copy, so the cached entry keeps the stored values
+	dashboard.TopCustomers = slices.Clone(dashboard.TopCustomers)
+	if err := middlewares.MaskFields(c, models.ResourceOrders, dashboard.TopCustomers); err != nil {
+		return err
+	}
+
+	return c.JSON(dashboard)
+}
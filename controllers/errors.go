@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"errors"
+	"go-admin/util"
+
+	"gorm.io/gorm"
+)
+
+// lookupError turns gorm.ErrRecordNotFound into a 404 naming the missing entity
+// (e.g., lookupError(err, "product not found")); other errors are returned unchanged
+func lookupError(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NotFound(message)
+	}
+	return err
+}
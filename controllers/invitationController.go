@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"errors"
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/middlewares"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// AllInvitations retrieves a paginated list of open (not accepted, not revoked) invitations
+// Expired invitations are included so they can be resent
+// Invitee email addresses are masked unless the caller holds users.email:read
+// Query parameter: page (defaults to 1 if not provided)
+func AllInvitations(c fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+
+	result, err := models.Paginate(database.DB.WithContext(c), &models.Invitation{}, page)
+	if err != nil {
+		return err
+	}
+	if err := middlewares.MaskFields(c, models.ResourceUsers, result["data"]); err != nil {
+		return err
+	}
+
+	return c.JSON(result)
+}
+
+// ResendInvitation issues a new token for an open invitation and emails it again
+// The previous link stops working and the expiry is reset; the change is recorded in the audit log
+// URL parameter: id (invitation identifier)
+func ResendInvitation(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var invitation models.Invitation
+	if err := database.DB.WithContext(c).Preload("User").Where("id = ?", id).First(&invitation).Error; err != nil {
+		return lookupError(err, "invitation not found")
+	}
+
+	// Accepted and revoked invitations cannot be resent
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return util.NotFound("invitation not found")
+	}
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the invitation for the audit log
+		before := invitation
+
+		if err := issueInvitation(tx, &invitation, invitation.User); err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityInvitations, invitation.Id, before, invitation)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := middlewares.MaskFields(c, models.ResourceUsers, &invitation); err != nil {
+		return err
+	}
+
+	return c.JSON(invitation)
+}
+
+// RevokeInvitation cancels an open invitation so its link can no longer be used
+// The pending user account is kept and can be re-invited or deleted separately
+// The revocation is recorded in the audit log
+// URL parameter: id (invitation identifier)
+func RevokeInvitation(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the invitation for the audit log
+		var invitation models.Invitation
+		if err := tx.Where("id = ?", id).First(&invitation).Error; err != nil {
+			return lookupError(err, "invitation not found")
+		}
+
+		// Only invitations that are still open can be revoked
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+			Update("revoked_at", &now)
+
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return util.NotFound("invitation not found")
+		}
+
+		after := invitation
+		after.RevokedAt = &now
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityInvitations, invitation.Id, invitation, after)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "invitation revoked",
+	})
+}
+
+// AcceptInvitation activates an invited account by setting its password
+// Public endpoint - the invitation token itself authenticates the request
+// Tokens are single-use: the invitation is consumed with a conditional update before the
+// password is set, so concurrent requests with the same token cannot both succeed
+// Request body: { "token": string, "password": string, "password_confirm": string }
+func AcceptInvitation(c fiber.Ctx) error {
+	var request dto.AcceptInvitation
+
+	// Parse and validate JSON request body (including password confirmation)
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	// Look up invitation by token hash
+	var invitation models.Invitation
+	err := database.DB.WithContext(c).Where("token_hash = ?", models.HashInvitationToken(request.Token)).First(&invitation).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if invitation.Id == 0 || !invitation.IsPending() {
+		return util.BadRequest("invitation is invalid or has expired")
+	}
+
+	user := models.User{
+		Id: invitation.UserId,
+	}
+	user.SetPassword(request.Password)
+
+	// Consume the invitation and activate the account atomically
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Only one request can claim the token; a concurrent accept, resend or revoke leaves no row to update
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", invitation.Id, invitation.TokenHash, now).
+			Update("accepted_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return util.BadRequest("invitation is invalid or has expired")
+		}
+
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"password": user.Password,
+			"pending":  false,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "invitation accepted",
+	})
+}
+
+// issueInvitation generates a new token for the invitation, saves it and emails the link to the user
+// Must be called inside a transaction so the invitation is not persisted if sending fails
+// Link base URL is configured via INVITATION_URL and lifetime via INVITATION_TTL (default 72h)
+func issueInvitation(tx *gorm.DB, invitation *models.Invitation, user models.User) error {
+	token, err := invitation.NewToken(util.GetenvDuration("INVITATION_TTL", 72*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Save(invitation).Error; err != nil {
+		return err
+	}
+
+	link := util.Getenv("INVITATION_URL", "http://localhost:3000/invitations/accept") + "?token=" + token
+
+	return util.SendMail(user.Email, "You have been invited to the admin dashboard",
+		"Hello "+user.FirstName+",\n\n"+
+			"An account has been created for you. Follow the link below to choose your password:\n\n"+
+			link+"\n\n"+
+			"This link can be used once and expires on "+invitation.ExpiresAt.Format(time.RFC1123)+".\n")
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// AllOptionTypes retrieves all option types (e.g., size, color) ordered by name
+// Typically used when defining the variants of a product
+func AllOptionTypes(c fiber.Ctx) error {
+	var optionTypes []models.OptionType
+
+	if err := database.DB.WithContext(c).Order("name").Find(&optionTypes).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(optionTypes)
+}
+
+// CreateOptionType creates a new option type
+// Fails with 409 if the name is already taken
+// Request body: see dto.OptionType (name)
+func CreateOptionType(c fiber.Ctx) error {
+	var request dto.OptionType
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	optionType := models.OptionType{
+		Name: request.Name,
+	}
+
+	// Persist new option type to database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&optionType).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCreate, models.EntityOptionTypes, optionType.Id, nil, optionType)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(optionType)
+}
+
+// UpdateOptionType renames an existing option type
+// Fails with 404 if the option type does not exist and with 409 if the name is already taken
+// Request body: see dto.OptionType (name)
+// URL parameter: id (option type identifier to update)
+func UpdateOptionType(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var request dto.OptionType
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	var optionType models.OptionType
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the option type for the audit log
+		if err := tx.Where("id = ?", id).First(&optionType).Error; err != nil {
+			return lookupError(err, "option type not found")
+		}
+		before := optionType
+
+		optionType.Name = request.Name
+		if err := tx.Model(&optionType).Update("name", optionType.Name).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityOptionTypes, optionType.Id, before, optionType)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(optionType)
+}
+
+// DeleteOptionType permanently removes an option type
+// Refuses with 409 Conflict while variants still use it
+// Fails with 404 if the option type does not exist
+// URL parameter: id (option type identifier to delete)
+func DeleteOptionType(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var optionType models.OptionType
+		if err := tx.Where("id = ?", id).First(&optionType).Error; err != nil {
+			return lookupError(err, "option type not found")
+		}
+
+		// Foreign keys refuse the deletion while variant options reference it
+		if err := tx.Delete(&optionType).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityOptionTypes, optionType.Id, optionType, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "option type deleted",
+	})
+}
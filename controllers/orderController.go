@@ -1,58 +1,78 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/csv"
 	"go-admin/database"
+	"go-admin/metrics"
+	"go-admin/middlewares"
 	"go-admin/models"
-	"os"
+	"io"
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
-// AllOrders retrieves a paginated list of all orders with their associated items
+// AllOrders retrieves a paginated list of orders with their associated items
+// Only orders visible under the user's row-level policies are returned
 // Uses the generic Paginate function for consistent pagination response format
+// Customer email addresses are masked unless the caller holds orders.email:read
 // Query parameter: page (defaults to 1 if not provided)
 func AllOrders(c fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
-	return c.JSON(models.Paginate(database.DB, &models.Order{}, page))
+
+	db := database.DB.WithContext(c).Scopes(middlewares.PolicyScope(c, models.ResourceOrders, models.ActionRead)).Session(&gorm.Session{})
+	result, err := models.Paginate(db, &models.Order{}, page)
+	if err != nil {
+		return err
+	}
+	if err := middlewares.MaskFields(c, models.ResourceOrders, result["data"]); err != nil {
+		return err
+	}
+
+	return c.JSON(result)
 }
 
-// Export generates a CSV file containing all orders and order items
+// Export generates a CSV file containing the orders and order items visible to the user
 // Creates a structured export file suitable for spreadsheet applications
-// The CSV file is generated and sent to the client as a download
+// The CSV is built in memory for each request and sent to the client as an order.csv download,
+// so concurrent exports never share a file
+// Customer email addresses are masked unless the caller holds orders.email:read
 func Export(c fiber.Ctx) error {
-	filePath := "./csv/order.csv"
+	// Generate CSV data with order data restricted by row-level policies
+	db := database.DB.WithContext(c).Scopes(middlewares.PolicyScope(c, models.ResourceOrders, models.ActionRead))
+	readable, err := middlewares.ReadableFields(c, models.ResourceOrders)
+	if err != nil {
+		return err
+	}
 
-	// Generate CSV file with order data
-	if err := CreateFile(filePath); err != nil {
+	var data bytes.Buffer
+	if err := WriteOrders(db, &data, readable); err != nil {
 		return err
 	}
 
-	// Send file to client as download
-	return c.Download(filePath)
+	// Send data to client as download
+	c.Attachment("order.csv")
+	return c.Send(data.Bytes())
 }
 
-// CreateFile generates a CSV file with order and order item data
+// WriteOrders writes order and order item data as CSV to w
 // CSV structure: each order has one header row with customer info,
 // followed by one row per order item (empty cells for customer columns)
 // This format allows visual grouping of items under their parent order
-func CreateFile(filePath string) error {
-	// Create CSV file
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// Initialize CSV writer with automatic flushing
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// Orders are read through db, so callers can restrict them with scopes
+// Protected fields the caller may not read (see readable) are masked
+func WriteOrders(db *gorm.DB, w io.Writer, readable func(field string) bool) error {
+	// Initialize CSV writer (flushed once every row is written)
+	writer := csv.NewWriter(w)
 
 	var orders []models.Order
 
-	// Load all orders with preloaded order items
-	database.DB.Preload("OrderItems").Find(&orders)
+	// Load orders with preloaded order items
+	if err := db.Preload("OrderItems").Find(&orders).Error; err != nil {
+		return err
+	}
 
 	// Write CSV header row
 	writer.Write([]string{
@@ -61,6 +81,8 @@ func CreateFile(filePath string) error {
 
 	// Write order data to CSV
 	for _, order := range orders {
+		order.MaskFields(readable)
+
 		// Write order header row with customer information
 		data := []string{
 			strconv.Itoa(int(order.Id)),
@@ -89,6 +111,13 @@ func CreateFile(filePath string) error {
 			}
 		}
 	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	metrics.OrdersExported.Add(float64(len(orders)))
 	return nil
 }
 
@@ -100,18 +129,22 @@ type Sales struct {
 }
 
 // Chart retrieves daily sales data aggregated by date
-// Uses raw SQL to group orders by creation date and calculate daily totals
+// Groups orders by creation date and calculates daily totals
+// Only orders visible under the user's row-level policies are included
 // Returns data formatted for chart visualization libraries
 func Chart(c fiber.Ctx) error {
 	var sales []Sales
 
-	// Execute raw SQL to aggregate daily sales
+	// Aggregate daily sales
 	// Groups by date and sums (price * quantity) for all order items
-	database.DB.Raw(`
-		SELECT DATE_FORMAT(o.create_at, '%Y-%m-%d') as date, SUM(oi.price*oi.quantity) as sum 
-		FROM orders o 
-		JOIN order_items oi on o.id=oi.order_id 
-		GROUP BY date
-		`).Scan(&sales)
+	err := database.DB.WithContext(c).Table("orders").
+		Select("DATE_FORMAT(orders.create_at, '%Y-%m-%d') as date, SUM(order_items.price*order_items.quantity) as sum").
+		Joins("JOIN order_items on orders.id=order_items.order_id").
+		Scopes(middlewares.PolicyScope(c, models.ResourceOrders, models.ActionRead)).
+		Group("date").
+		Scan(&sales).Error
+	if err != nil {
+		return err
+	}
 	return c.JSON(sales)
 }
@@ -2,9 +2,14 @@ package controllers
 
 import (
 	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/middlewares"
 	"go-admin/models"
+	"go-admin/util"
+	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
 // AllPermissions retrieves all permissions from the database
@@ -13,24 +18,162 @@ func AllPermissions(c fiber.Ctx) error {
 	var Permissions []models.Permission
 
 	// Query all permission records
-	database.DB.Find(&Permissions)
+	if err := database.DB.WithContext(c).Find(&Permissions).Error; err != nil {
+		return err
+	}
 
 	return c.JSON(Permissions)
 }
 
 // CreatePermission creates a new permission record in the database
 // Used to extend the RBAC system with new permission capabilities
-// Requires permission name in request body
+// Requires permission name in request body; names must be unique
 func CreatePermission(c fiber.Ctx) error {
-	var Permission models.Permission
+	var request dto.Permission
 
-	// Parse JSON request body into Permission struct
-	if err := c.Bind().Body(&Permission); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Persist new permission to database
-	database.DB.Create(&Permission)
+	Permission := models.Permission{
+		Name: request.Name,
+	}
+
+	// Reject names that are already taken
+	if taken, err := permissionNameTaken(c, Permission.Name, 0); err != nil {
+		return err
+	} else if taken {
+		return util.Conflict("permission name already exists")
+	}
+
+	// Persist new permission to database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Permission).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCreate, models.EntityPermissions, Permission.Id, nil, Permission)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Cached permission sets may predate the new permission
+	middlewares.InvalidatePermissions()
 
 	return c.JSON(Permission)
 }
+
+// GetPermission retrieves a specific permission by ID
+// URL parameter: id (permission identifier)
+func GetPermission(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var permission models.Permission
+
+	// Find permission by primary key
+	if err := database.DB.WithContext(c).Where("id = ?", id).First(&permission).Error; err != nil {
+		return lookupError(err, "permission not found")
+	}
+
+	return c.JSON(permission)
+}
+
+// UpdatePermission renames an existing permission
+// Roles granted the permission keep it under its new name
+// URL parameter: id (permission identifier to update)
+func UpdatePermission(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var permission models.Permission
+	if err := database.DB.WithContext(c).Where("id = ?", id).First(&permission).Error; err != nil {
+		return lookupError(err, "permission not found")
+	}
+
+	before := permission
+
+	// Parse and validate updated permission data from request body
+	var request dto.Permission
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+	permission.Name = request.Name
+
+	// Reject names that are already taken by another permission
+	if taken, err := permissionNameTaken(c, permission.Name, permission.Id); err != nil {
+		return err
+	} else if taken {
+		return util.Conflict("permission name already exists")
+	}
+
+	// Update permission record in database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&permission).Update("name", permission.Name).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityPermissions, permission.Id, before, permission)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Cached permission sets refer to permissions by name
+	middlewares.InvalidatePermissions()
+
+	return c.JSON(permission)
+}
+
+// DeletePermission permanently removes a permission from the database
+// Refuses with 409 Conflict while the permission is still granted to any role;
+// the foreign key on role_permissions enforces the same rule at the database level
+// URL parameter: id (permission identifier to delete)
+func DeletePermission(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	// Refuse to delete permissions that are still in use
+	var roles int64
+	if err := database.DB.WithContext(c).Model(&models.RolePermission{}).Where("permission_id = ?", id).Count(&roles).Error; err != nil {
+		return err
+	}
+
+	if roles > 0 {
+		return util.Conflict("permission is granted to " + strconv.FormatInt(roles, 10) + " role(s)")
+	}
+
+	var permission models.Permission
+
+	// Delete permission record from database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&permission).Error; err != nil {
+			return lookupError(err, "permission not found")
+		}
+
+		if err := tx.Delete(&permission).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityPermissions, permission.Id, permission, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	middlewares.InvalidatePermissions()
+
+	return c.JSON(fiber.Map{
+		"message": "permission deleted",
+	})
+}
+
+// permissionNameTaken reports whether another permission (other than exceptId) already uses name
+func permissionNameTaken(c fiber.Ctx, name string, exceptId uint) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(c).Model(&models.Permission{}).Where("name = ? AND id <> ?", name, exceptId).Count(&count).Error
+	return count > 0, err
+}
+
+// AllRoutes lists every API route together with the permission it requires
+// Routes without a permission are either public or only require authentication
+// Useful for deciding which permissions a role needs to reach a screen
+func AllRoutes(c fiber.Ctx) error {
+	return c.JSON(middlewares.DeclaredRoutes())
+}
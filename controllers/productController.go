@@ -2,86 +2,238 @@ package controllers
 
 import (
 	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/metrics"
 	"go-admin/models"
+	"go-admin/util"
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
 // AllProducts retrieves a paginated list of products from the database
 // Uses the generic Paginate function for consistent pagination response format
-// Query parameter: page (defaults to 1 if not provided)
+// Products at or below LOW_STOCK_THRESHOLD are flagged with low_stock
+// Query parameters:
+//   - page: page number (defaults to 1 if not provided)
+//   - low_stock: when "true", only return products flagged as low on stock
+//   - category_id: only return products filed under this category or any of its descendants
+//   - tag_id: only return products carrying this tag
 func AllProducts(c fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
-	return c.JSON(models.Paginate(database.DB, &models.Product{}, page))
+
+	db := database.DB.WithContext(c)
+	if c.Query("low_stock") == "true" {
+		db = db.Where("products.stock <= ?", lowStockThreshold)
+	}
+	if categoryId, err := strconv.Atoi(c.Query("category_id")); err == nil {
+		category, err := findCategory(c, categoryId)
+		if err != nil {
+			return err
+		}
+		db = db.Scopes(models.InCategory(category))
+	}
+	if tagId, err := strconv.Atoi(c.Query("tag_id")); err == nil {
+		db = db.Scopes(models.WithTag(uint(tagId)))
+	}
+
+	result, err := models.Paginate(db.Session(&gorm.Session{}), &models.Product{}, page)
+	if err != nil {
+		return err
+	}
+	if products, ok := result["data"].([]models.Product); ok {
+		for i := range products {
+			products[i].FlagLowStock(lowStockThreshold)
+		}
+	}
+
+	return c.JSON(result)
 }
 
 // CreateProduct creates a new product record in the database
 // Adds a new item to the product catalog
-// Request body should contain: title, description, image, price
+// A positive initial stock is recorded as a receipt in the stock ledger
+// Request body: see dto.CreateProduct (title, description, image, price, stock, category_id, tag_ids)
 func CreateProduct(c fiber.Ctx) error {
-	var product models.Product
+	var request dto.CreateProduct
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&product); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Persist new product to database
-	database.DB.Create(&product)
+	product := models.Product{
+		Title:       request.Title,
+		Description: request.Description,
+		Image:       request.Image,
+		Price:       request.Price,
+		CategoryId:  request.CategoryId,
+		TagIds:      request.TagIds,
+	}
+
+	// Assign the requested tags
+	product.AssignTags()
+
+	// Persist new product to database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Only write product_tags rows - the referenced tags already exist
+		if err := tx.Omit("Tags.*").Create(&product).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, models.AuditCreate, models.EntityProducts, product.Id, nil, product); err != nil {
+			return err
+		}
+
+		if request.Stock > 0 {
+			if _, err := recordStockMovement(tx, c, product.Id, models.StockReceipt, request.Stock, "initial stock"); err != nil {
+				return err
+			}
+		}
+
+		// Reload the product with its stock, category and tags
+		return productWithRelations(tx).Where("id = ?", product.Id).First(&product).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if request.Stock > 0 {
+		metrics.StockMovements.WithLabelValues(models.StockReceipt).Inc()
+	}
+	product.FlagLowStock(lowStockThreshold)
 
 	return c.JSON(product)
 }
 
 // GetProduct retrieves a specific product by ID
 // Used for viewing individual product details
+// Fails with 404 if the product does not exist
 // URL parameter: id (product identifier)
 func GetProduct(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	product := models.Product{
-		Id: uint(id),
-	}
+	var product models.Product
 
-	// Find product by primary key
-	database.DB.Find(&product)
+	// Find product by primary key with its category and tags
+	if err := productWithRelations(database.DB.WithContext(c)).Where("id = ?", id).First(&product).Error; err != nil {
+		return lookupError(err, "product not found")
+	}
+	product.FlagLowStock(lowStockThreshold)
 
 	return c.JSON(product)
 }
 
 // UpdateProduct updates an existing product's information
-// Allows modification of: title, description, image, and price
+// Allows modification of: title, description, image, price and category; omitted fields are left unchanged
+// When tag_ids is present, the product's tags are replaced with the given list
+// Fails with 404 if the product does not exist and with 422 if the category does not exist
+// Request body: see dto.UpdateProduct
 // URL parameter: id (product identifier to update)
 func UpdateProduct(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
+	// Parse and validate updated product data from request body
+	var request dto.UpdateProduct
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
 	product := models.Product{
-		Id: uint(id),
+		Id:     uint(id),
+		TagIds: request.TagIds,
 	}
 
-	// Parse updated product data from request body
-	if err := c.Bind().Body(&product); err != nil {
-		return err
+	// Collect the fields that were sent
+	changes := map[string]interface{}{}
+	if request.Title != nil {
+		changes["title"] = *request.Title
+	}
+	if request.Description != nil {
+		changes["description"] = *request.Description
+	}
+	if request.Image != nil {
+		changes["image"] = *request.Image
+	}
+	if request.Price != nil {
+		changes["price"] = *request.Price
+	}
+	if request.CategoryId.Set {
+		// Category may also be cleared with null
+		categoryId := request.CategoryId.Value
+		if categoryId != nil {
+			if _, err := findCategory(c, int(*categoryId)); err != nil {
+				return util.Invalid("invalid request", fiber.Map{"category_id": "refers to a missing record"})
+			}
+		}
+		changes["category_id"] = categoryId
 	}
 
-	// Update product record in database
-	database.DB.Model(&product).Updates(product)
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the product for the audit log
+		var before models.Product
+		if err := productWithRelations(tx).Where("id = ?", product.Id).First(&before).Error; err != nil {
+			return lookupError(err, "product not found")
+		}
+
+		// Update product record in database (tags are handled separately below)
+		if len(changes) > 0 {
+			if err := tx.Model(&product).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace tag assignments when a tag list was provided
+		if product.TagIds != nil {
+			product.AssignTags()
+			if err := tx.Model(&product).Omit("Tags.*").Association("Tags").Replace(product.Tags); err != nil {
+				return err
+			}
+		}
+
+		if err := productWithRelations(tx).Where("id = ?", product.Id).First(&product).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityProducts, product.Id, before, product)
+	})
+	if err != nil {
+		return err
+	}
+	product.FlagLowStock(lowStockThreshold)
 
 	return c.JSON(product)
 }
 
+// productWithRelations preloads the category and tags of the products loaded through db
+func productWithRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Category").Preload("Tags")
+}
+
 // DeleteProduct permanently removes a product from the database
 // This is a destructive operation - ensure proper authorization is in place
+// Refuses with 409 Conflict once stock movements were recorded for the product, so the
+// stock ledger keeps its history; fails with 404 if the product does not exist
 // URL parameter: id (product identifier to delete)
 func DeleteProduct(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	product := models.Product{
-		Id: uint(id),
+	// Delete product record from database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Where("id = ?", id).First(&product).Error; err != nil {
+			return lookupError(err, "product not found")
+		}
+
+		if err := tx.Delete(&product).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityProducts, product.Id, product, nil)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Delete product record from database
-	database.DB.Delete(&product)
-
-	return nil
+	return c.JSON(fiber.Map{
+		"message": "product deleted",
+	})
 }
@@ -1,133 +1,393 @@
 package controllers
 
 import (
+	"errors"
 	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/middlewares"
 	"go-admin/models"
+	"go-admin/util"
 	"strconv"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
-// AllRoles retrieves all roles with their associated permissions
+// AllRoles retrieves all roles with their associated permissions and row-level policies
 // Typically used for role management UI to display available roles
 func AllRoles(c fiber.Ctx) error {
 	var roles []models.Role
 
-	// Load all roles with preloaded permissions
-	database.DB.Preload("Permissions").Find(&roles)
+	// Load all roles with preloaded permissions and policies
+	if err := database.DB.WithContext(c).Preload("Permissions").Preload("Policies").Find(&roles).Error; err != nil {
+		return err
+	}
 
 	return c.JSON(roles)
 }
 
 // CreateRole creates a new role with associated permissions
 // Establishes a many-to-many relationship between roles and permissions
-// Request body: { "name": string, "permissions": []string (permission IDs) }
+// Request body: { "name": string, "permissions": []string (permission IDs), "parent_id": number|null,
+// "policies": [{ "resource": string, "field": string, "value": string }] } (see dto.Role)
+// Fails with 422 for a missing name, missing permissions, parent assignments that
+// reference a missing role or form an inheritance cycle, and policies on unsupported
+// resources, fields or user attributes
 func CreateRole(c fiber.Ctx) error {
-	var roleDTO fiber.Map
+	var request dto.Role
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&roleDTO); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Extract permissions array from request
-	list := roleDTO["permissions"].([]interface{})
-
-	// Build Permission slice from provided IDs
-	permissions := make([]models.Permission, len(list))
-	for i, permissionId := range list {
-		id, _ := strconv.Atoi(permissionId.(string))
-		permissions[i] = models.Permission{
-			Id: uint(id),
+	// Validate optional parent role before creating anything
+	parentId := roleParentId(request)
+	if parentId != nil {
+		if err := models.ValidateParent(database.DB.WithContext(c), 0, *parentId); err != nil {
+			return invalidParent(err)
 		}
 	}
 
-	// Create role with associated permissions
+	// Validate optional row-level policies
+	policies, err := rolePolicies(request)
+	if err != nil {
+		return invalidPolicies()
+	}
+
+	// Create role with associated permissions and policies
 	role := models.Role{
-		Name:        roleDTO["name"].(string),
-		Permissions: permissions,
+		Name:        request.Name,
+		ParentId:    parentId,
+		Permissions: rolePermissions(request),
+		Policies:    policies,
 	}
 
-	// Persist role and create associations in join table
-	database.DB.Create(&role)
+	// Persist role and create associations in join table, together with its audit entry
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCreate, models.EntityRoles, role.Id, nil, roleSnapshot(tx, role.Id))
+	})
+	if err != nil {
+		return err
+	}
+
+	// Discard any permission set cached under this role ID
+	middlewares.InvalidateRole(role.Id)
 
 	return c.JSON(role)
 }
 
 // GetRole retrieves a specific role by ID with its associated permissions
 // Used for viewing role details and permission assignments
+// Fails with 404 if the role does not exist
 // URL parameter: id (role identifier)
 func GetRole(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	role := models.Role{
-		Id: uint(id),
+	var role models.Role
+
+	// Find role and eagerly load permissions and policies
+	if err := database.DB.WithContext(c).Preload("Permissions").Preload("Policies").Where("id = ?", id).First(&role).Error; err != nil {
+		return lookupError(err, "role not found")
 	}
 
-	// Find role and eagerly load permissions
-	database.DB.Preload("Permissions").Find(&role)
+	// Resolve permissions inherited from ancestor roles
+	if err := role.LoadInheritedPermissions(database.DB.WithContext(c)); err != nil {
+		return err
+	}
 
 	return c.JSON(role)
 }
 
-// RolePermission represents the role_permissions join table structure
-// Used for direct manipulation of the many-to-many relationship
-type RolePermission struct {
-	RoleID       uint `gorm:"primaryKey"` // Foreign key to roles table
-	PermissionID uint `gorm:"primaryKey"` // Foreign key to permissions table
+// roleSnapshot loads a role with its direct permissions and policies for the audit log
+func roleSnapshot(db *gorm.DB, id uint) models.Role {
+	var role models.Role
+	db.Preload("Permissions").Preload("Policies").Where("id = ?", id).Find(&role)
+	return role
+}
+
+// rolePermissions converts the permission IDs of a role request into Permission references
+// Only the IDs are set; the referenced permissions already exist
+func rolePermissions(request dto.Role) []models.Permission {
+	permissions := make([]models.Permission, len(request.Permissions))
+	for i, permissionId := range request.Permissions {
+		permissions[i] = models.Permission{
+			Id: uint(permissionId),
+		}
+	}
+	return permissions
+}
+
+// roleParentId returns the parent role ID of a role request
+// A missing parent_id, null or 0 means no parent
+func roleParentId(request dto.Role) *uint {
+	if request.ParentId.Value == nil || *request.ParentId.Value == 0 {
+		return nil
+	}
+	parentId := uint(*request.ParentId.Value)
+	return &parentId
+}
+
+// invalidParent reports a rejected parent role assignment as a 422 validation error
+func invalidParent(err error) error {
+	message := "parent role not found"
+	if errors.Is(err, models.ErrRoleCycle) {
+		message = err.Error()
+	}
+
+	return util.Invalid("invalid role", fiber.Map{"parent_id": message})
+}
+
+// rolePolicies converts and validates the row-level policies of a role request
+// Returns models.ErrInvalidPolicy if any entry targets an unsupported resource, field or user attribute
+func rolePolicies(request dto.Role) ([]models.Policy, error) {
+	policies := make([]models.Policy, len(request.Policies.Value))
+	for i, entry := range request.Policies.Value {
+		policies[i] = models.Policy{
+			Resource: entry.Resource,
+			Field:    entry.Field,
+			Value:    entry.Value,
+		}
+
+		if err := policies[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return policies, nil
+}
+
+// invalidPolicies reports rejected row-level policies as a 422 validation error
+func invalidPolicies() error {
+	return util.Invalid("invalid role", fiber.Map{"policies": models.ErrInvalidPolicy.Error()})
+}
+
+// findRole checks that the role named by id exists
+// Fails with 404 if it does not
+func findRole(c fiber.Ctx, id int) error {
+	var role models.Role
+	err := database.DB.WithContext(c).Select("id").Where("id = ?", id).First(&role).Error
+	return lookupError(err, "role not found")
 }
 
 // UpdateRole updates an existing role's name and permission assignments
 // Replaces all existing permission associations with the new set
+// When roles:update requires approval, a pending change request is created instead
+// Fails with 404 if the role does not exist and with 422 for the same reasons as CreateRole
 // URL parameter: id (role identifier to update)
 func UpdateRole(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	var roleDTO fiber.Map
+	var request dto.Role
 
-	// Parse JSON request body
-	if err := c.Bind().Body(&roleDTO); err != nil {
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	// Extract and process permissions array
-	list := roleDTO["permissions"].([]interface{})
-	permissions := make([]models.Permission, len(list))
-	for i, permissionId := range list {
-		id, _ := strconv.Atoi(permissionId.(string))
-		permissions[i] = models.Permission{
-			Id: uint(id),
+	return updateRole(c, id, request, false)
+}
+
+// updateRole validates and applies a role update, or holds it for approval when it is required
+// The request is validated before it is held, and again when the approved change is applied
+// approved is set when applying an approved change request
+func updateRole(c fiber.Ctx, id int, request dto.Role, approved bool) error {
+	if err := findRole(c, id); err != nil {
+		return err
+	}
+
+	// Validate optional parent role - the new parent must not descend from this role
+	parentId := roleParentId(request)
+	if parentId != nil {
+		if err := models.ValidateParent(database.DB.WithContext(c), uint(id), *parentId); err != nil {
+			return invalidParent(err)
 		}
 	}
 
-	// Remove all existing permission associations
-	var rolePermission RolePermission
-	database.DB.Table("role_permissions").Where("role_id = ?", id).Delete(&rolePermission)
+	// Validate optional row-level policies
+	policies, err := rolePolicies(request)
+	if err != nil {
+		return invalidPolicies()
+	}
+
+	if !approved && approvalRequired[models.ChangeUpdateRole] {
+		return requestApproval(c, models.ChangeUpdateRole, uint(id), request)
+	}
 
-	// Update role with new name and permissions
 	role := models.Role{
 		Id:          uint(id),
-		Name:        roleDTO["name"].(string),
-		Permissions: permissions,
+		Name:        request.Name,
+		Permissions: rolePermissions(request),
+	}
+
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the role for the audit log
+		before := roleSnapshot(tx, role.Id)
+
+		// Remove all existing permission associations
+		if err := tx.Where("role_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+
+		// Update role with new name and permissions
+		if err := tx.Model(&role).Updates(role).Error; err != nil {
+			return err
+		}
+
+		// Update parent explicitly so it can also be cleared with null
+		if request.ParentId.Set {
+			role.ParentId = parentId
+			if err := tx.Model(&role).Update("parent_id", parentId).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace row-level policies when a policy list was provided
+		if request.Policies.Set {
+			if err := tx.Where("role_id = ?", role.Id).Delete(&models.Policy{}).Error; err != nil {
+				return err
+			}
+			if len(policies) > 0 {
+				for i := range policies {
+					policies[i].RoleId = role.Id
+				}
+				if err := tx.Create(&policies).Error; err != nil {
+					return err
+				}
+			}
+			role.Policies = policies
+		}
+
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityRoles, role.Id, before, roleSnapshot(tx, role.Id))
+	})
+	if err != nil {
+		return err
 	}
-	database.DB.Model(&role).Updates(role)
+
+	// Users of this role and its descendants must see the new permission set immediately
+	middlewares.InvalidateRole(role.Id)
 
 	return c.JSON(role)
 }
 
 // DeleteRole permanently removes a role from the database
-// Cascades deletion to role_permissions join table associations
+// Cascades deletion to role_permissions join table associations and row-level policies;
+// roles inheriting from it lose their parent
+// Refuses with 409 Conflict while users are still assigned to the role, unless the
+// reassign_to query parameter names another role that takes over those users
 // This is a destructive operation - ensure proper authorization
+// When roles:delete requires approval, a pending change request is created instead
+// Fails with 404 if the role does not exist
 // URL parameter: id (role identifier to delete)
+// Query parameter: reassign_to (optional role ID receiving the role's users)
 func DeleteRole(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
+	reassignTo, _ := strconv.Atoi(c.Query("reassign_to"))
+
+	return deleteRole(c, id, reassignTo, false)
+}
+
+// deleteRole checks and applies a role deletion, or holds it for approval when it is required
+// The deletion is checked before it is held, and again when the approved change is applied
+// approved is set when applying an approved change request
+func deleteRole(c fiber.Ctx, id int, reassignTo int, approved bool) error {
+	if err := findRole(c, id); err != nil {
+		return err
+	}
 
 	role := models.Role{
 		Id: uint(id),
 	}
 
-	// Delete role (GORM handles join table cleanup automatically)
-	database.DB.Delete(&role)
+	var users int64
+	if err := database.DB.WithContext(c).Model(&models.UserRole{}).Where("role_id = ?", id).Count(&users).Error; err != nil {
+		return err
+	}
+
+	// Refuse to orphan users unless a replacement role was given
+	if users > 0 && reassignTo == 0 {
+		return util.Conflict("role is assigned to " + strconv.FormatInt(users, 10) + " user(s)")
+	}
+
+	if reassignTo != 0 {
+		var target int64
+		if err := database.DB.WithContext(c).Model(&models.Role{}).Where("id = ?", reassignTo).Count(&target).Error; err != nil {
+			return err
+		}
 
+		if target == 0 || reassignTo == id {
+			return util.Invalid("invalid reassignment role", fiber.Map{"reassign_to": "must name another existing role"})
+		}
+
+		// Moving users into the replacement role grants it, so it requires authority over it
+		if err := authorizeReassignment(c, id, reassignTo); err != nil {
+			return err
+		}
+	}
+
+	if !approved && approvalRequired[models.ChangeDeleteRole] {
+		return requestApproval(c, models.ChangeDeleteRole, uint(id), fiber.Map{"reassign_to": reassignTo})
+	}
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the role for the audit log
+		before := roleSnapshot(tx, role.Id)
+
+		if reassignTo != 0 {
+			// Move users to the replacement role, skipping users who already hold it
+			// Time-bound assignments keep their validity window
+			if err := tx.Exec("INSERT IGNORE INTO user_roles (user_id, role_id, starts_at, expires_at, granted_by) SELECT user_id, ?, starts_at, expires_at, granted_by FROM user_roles WHERE role_id = ?", reassignTo, id).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("role_id = ?", id).Delete(&models.UserRole{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Delete role (foreign keys remove its grants and policies and detach child roles)
+		result := tx.Delete(&role)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return util.NotFound("role not found")
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityRoles, role.Id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Revoke cached access granted through the deleted role
+	middlewares.InvalidateRole(role.Id)
+	if reassignTo != 0 {
+		middlewares.InvalidateRole(uint(reassignTo))
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "role deleted",
+	})
+}
+
+// authorizeReassignment checks that the authenticated user may move the holders of a role to reassignTo
+// Every holder receives the replacement role with the validity window of their current assignment,
+// which requires the same authority as granting it (see middlewares.AuthorizeRoleAssignment)
+func authorizeReassignment(c fiber.Ctx, roleId int, reassignTo int) error {
+	var grants []models.UserRole
+	err := database.DB.WithContext(c).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id", "email", "region") }).
+		Where("role_id = ?", roleId).
+		Find(&grants).Error
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		if err := middlewares.AuthorizeRoleAssignment(c, grant.User, []uint{uint(reassignTo)}, grant.ExpiresAt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
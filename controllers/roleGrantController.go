@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/middlewares"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserRoles retrieves the role assignments of a specific user, including their validity window
+// Expired assignments that the sweeper has not removed yet are still listed
+// Returns 404 if the user does not exist or is hidden by the caller's row-level policies
+// URL parameter: id (user identifier)
+func GetUserRoles(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	if _, err := visibleUser(c, database.DB.WithContext(c), uint(id), models.ActionRead); err != nil {
+		return err
+	}
+
+	var grants []models.UserRole
+	if err := database.DB.WithContext(c).Preload("Role").Where("user_id = ?", id).Find(&grants).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(grants)
+}
+
+// GrantUserRole assigns a role to a user, optionally for a limited time
+// Replaces the validity window when the user already holds the role
+// The calling admin is recorded as the grantor and notified by email before a time-bound grant expires
+// Request body: { "role_id": number, "starts_at": RFC 3339 time|null, "expires_at": RFC 3339 time|null }
+// Fails with 403 unless the caller holds roles:update, or every permission of the role and
+// grants it to someone else until no later than their own access ends
+// (see middlewares.AuthorizeRoleAssignment), with 404 if the user does not exist or is hidden by
+// the caller's row-level policies, and with 422 if the role does not
+// URL parameter: id (user identifier)
+func GrantUserRole(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var request dto.GrantRole
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	grant := models.UserRole{
+		RoleId:    request.RoleId,
+		StartsAt:  request.StartsAt,
+		ExpiresAt: request.ExpiresAt,
+	}
+
+	// A grant must end after it starts, and in the future
+	if grant.ExpiresAt != nil {
+		if !grant.ExpiresAt.After(time.Now()) || (grant.StartsAt != nil && !grant.ExpiresAt.After(*grant.StartsAt)) {
+			return util.Invalid("invalid role grant", fiber.Map{"expires_at": "must be in the future and after starts_at"})
+		}
+	}
+
+	// The user must exist and be visible
+	target, err := visibleUser(c, database.DB.WithContext(c), uint(id), models.ActionUpdate)
+	if err != nil {
+		return err
+	}
+
+	// Granting a role must not hand out more, or longer, access than the caller has
+	if err := middlewares.AuthorizeRoleAssignment(c, target, []uint{request.RoleId}, grant.ExpiresAt); err != nil {
+		return err
+	}
+
+	// Identify the granting admin from the JWT token
+	granterId, _ := util.ParseJWT(c.Cookies("jwt"))
+	grantedBy, _ := strconv.Atoi(granterId)
+
+	grant.UserId = uint(id)
+	if grantedBy > 0 {
+		granter := uint(grantedBy)
+		grant.GrantedBy = &granter
+	}
+
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot an existing assignment for the audit log
+		var before models.UserRole
+		if err := tx.Where("user_id = ? AND role_id = ?", grant.UserId, grant.RoleId).Find(&before).Error; err != nil {
+			return err
+		}
+
+		// Insert the assignment or replace the validity window of an existing one
+		err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"starts_at", "expires_at", "granted_by", "expiry_notified_at"}),
+		}).Create(&grant).Error
+		if err != nil {
+			return err
+		}
+
+		if before.UserId == 0 {
+			return recordAudit(tx, c, models.AuditCreate, models.EntityUserRoles, grant.UserId, nil, grant)
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityUserRoles, grant.UserId, before, grant)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The user's permission set changed
+	middlewares.InvalidateUser(grant.UserId)
+
+	return c.JSON(grant)
+}
+
+// RevokeUserRole removes a role assignment from a user immediately
+// Requires the same authority over the role as GrantUserRole, without the expiry limit (403 otherwise)
+// Fails with 404 if the user is hidden by the caller's row-level policies or does not hold the role
+// URL parameters: id (user identifier), roleId (role identifier)
+func RevokeUserRole(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	roleId, _ := strconv.Atoi(c.Params("roleId"))
+
+	var grant models.UserRole
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		target, err := visibleUser(c, tx, uint(id), models.ActionUpdate)
+		if err != nil {
+			return err
+		}
+
+		// Managing a role's assignments requires authority over the role
+		if err := middlewares.AuthorizeRoleRevocation(c, target, []uint{uint(roleId)}); err != nil {
+			return err
+		}
+
+		// Snapshot the assignment for the audit log
+		if err := tx.Where("user_id = ? AND role_id = ?", id, roleId).First(&grant).Error; err != nil {
+			return lookupError(err, "role assignment not found")
+		}
+
+		if err := tx.Where("user_id = ? AND role_id = ?", id, roleId).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityUserRoles, grant.UserId, grant, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The user's permission set changed
+	middlewares.InvalidateUser(uint(id))
+
+	return c.JSON(fiber.Map{
+		"message": "role revoked",
+	})
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/metrics"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lowStockThreshold is the stock level at or below which a product is flagged as low on stock
+// Configured with LOW_STOCK_THRESHOLD (default 5)
+var lowStockThreshold = util.GetenvInt("LOW_STOCK_THRESHOLD", 5)
+
+// AdjustStock records a stock movement for a product and updates its stock level
+// Receipts and returns add the quantity, sales subtract it, adjustments apply a signed quantity
+// Fails with 404 if the product does not exist, with 409 if the movement would take the
+// stock below zero, and with 422 for a quantity that does not fit the movement type
+// Request body: see dto.AdjustStock (type, quantity, reason)
+// URL parameter: id (product identifier)
+func AdjustStock(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	request, change, err := bindStockMovement(c)
+	if err != nil {
+		return err
+	}
+
+	var movement models.StockMovement
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		movement, err = recordStockMovement(tx, c, uint(id), request.Type, change, request.Reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.StockMovements.WithLabelValues(movement.Type).Inc()
+
+	return c.JSON(movement)
+}
+
+// AdjustVariantStock records a stock movement for a product variant and updates its stock level
+// Movements follow the same rules as AdjustStock
+// Fails with 404 if the variant does not exist or belongs to another product
+// Request body: see dto.AdjustStock (type, quantity, reason)
+// URL parameters: id (product identifier), variantId (variant identifier)
+func AdjustVariantStock(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	variantId, _ := strconv.Atoi(c.Params("variantId"))
+
+	request, change, err := bindStockMovement(c)
+	if err != nil {
+		return err
+	}
+
+	var movement models.StockMovement
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		movement, err = recordVariantStockMovement(tx, c, uint(id), uint(variantId), request.Type, change, request.Reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.StockMovements.WithLabelValues(movement.Type).Inc()
+
+	return c.JSON(movement)
+}
+
+// bindStockMovement parses and validates a stock movement request and returns its signed stock change
+// Fails with 422 for a quantity that does not fit the movement type
+func bindStockMovement(c fiber.Ctx) (dto.AdjustStock, int, error) {
+	var request dto.AdjustStock
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return request, 0, err
+	}
+
+	change, err := models.StockChange(request.Type, request.Quantity)
+	if err != nil {
+		return request, 0, util.Invalid("invalid stock movement", fiber.Map{"quantity": err.Error()})
+	}
+	return request, change, nil
+}
+
+// recordStockMovement applies a signed stock change to a product and appends it to the ledger
+// The product row is locked until the transaction ends, so concurrent movements cannot oversell
+// The change of the product's stock level is recorded in the audit log as well
+// Fails with 404 if the product does not exist and with 409 if the stock would drop below zero
+func recordStockMovement(tx *gorm.DB, c fiber.Ctx, productId uint, movementType string, change int, reason string) (models.StockMovement, error) {
+	var before models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", productId).First(&before).Error; err != nil {
+		return models.StockMovement{}, lookupError(err, "product not found")
+	}
+
+	if before.Stock+change < 0 {
+		return models.StockMovement{}, insufficientStock(before.Stock)
+	}
+
+	after := before
+	after.Stock += change
+	if err := tx.Model(&after).Update("stock", after.Stock).Error; err != nil {
+		return models.StockMovement{}, err
+	}
+
+	movement := models.StockMovement{
+		ProductId:  productId,
+		Type:       movementType,
+		Quantity:   change,
+		StockAfter: after.Stock,
+		Reason:     reason,
+	}
+	if err := appendStockMovement(tx, c, &movement); err != nil {
+		return models.StockMovement{}, err
+	}
+	return movement, recordAudit(tx, c, models.AuditUpdate, models.EntityProducts, productId, before, after)
+}
+
+// recordVariantStockMovement applies a signed stock change to a product variant and appends it to the ledger
+// Locks the variant row like recordStockMovement locks the product row
+// Fails with 404 if the variant does not exist or belongs to another product,
+// and with 409 if the stock would drop below zero
+func recordVariantStockMovement(tx *gorm.DB, c fiber.Ctx, productId uint, variantId uint, movementType string, change int, reason string) (models.StockMovement, error) {
+	var before models.Variant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND product_id = ?", variantId, productId).First(&before).Error
+	if err != nil {
+		return models.StockMovement{}, lookupError(err, "variant not found")
+	}
+
+	if before.Stock+change < 0 {
+		return models.StockMovement{}, insufficientStock(before.Stock)
+	}
+
+	after := before
+	after.Stock += change
+	if err := tx.Model(&after).Update("stock", after.Stock).Error; err != nil {
+		return models.StockMovement{}, err
+	}
+
+	movement := models.StockMovement{
+		ProductId:  productId,
+		VariantId:  &variantId,
+		Type:       movementType,
+		Quantity:   change,
+		StockAfter: after.Stock,
+		Reason:     reason,
+	}
+	if err := appendStockMovement(tx, c, &movement); err != nil {
+		return models.StockMovement{}, err
+	}
+	return movement, recordAudit(tx, c, models.AuditUpdate, models.EntityVariants, variantId, before, after)
+}
+
+// appendStockMovement stores a movement in the ledger on behalf of the authenticated user
+func appendStockMovement(tx *gorm.DB, c fiber.Ctx, movement *models.StockMovement) error {
+	// Identify the acting user from the JWT token
+	if id, err := util.ParseJWT(c.Cookies("jwt")); err == nil {
+		if actorId, err := strconv.Atoi(id); err == nil {
+			actor := uint(actorId)
+			movement.ActorId = &actor
+		}
+	}
+
+	return tx.Create(movement).Error
+}
+
+// insufficientStock reports a movement that would take the stock below zero as a 409 Conflict
+func insufficientStock(stock int) error {
+	return util.Conflict("insufficient stock").WithDetails(fiber.Map{"stock": stock})
+}
+
+// GetStockMovements retrieves the paginated stock ledger of a product, most recent first
+// Only movements of the product's own stock are returned; see GetVariantStockMovements for variants
+// Fails with 404 if the product does not exist
+// URL parameter: id (product identifier)
+// Query parameters:
+//   - page: page number (defaults to 1 if not provided)
+//   - type: only return movements of this type (receipt, sale, adjustment or return)
+func GetStockMovements(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var product models.Product
+	if err := database.DB.WithContext(c).Select("id").Where("id = ?", id).First(&product).Error; err != nil {
+		return lookupError(err, "product not found")
+	}
+
+	// Restrict the ledger to the requested product's own stock
+	return stockLedger(c, database.DB.WithContext(c).Where("product_id = ? AND variant_id IS NULL", id))
+}
+
+// GetVariantStockMovements retrieves the paginated stock ledger of a product variant, most recent first
+// Fails with 404 if the variant does not exist or belongs to another product
+// URL parameters: id (product identifier), variantId (variant identifier)
+// Query parameters: page and type, as for GetStockMovements
+func GetVariantStockMovements(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	variantId, _ := strconv.Atoi(c.Params("variantId"))
+
+	if _, err := findVariant(c, id, variantId); err != nil {
+		return err
+	}
+
+	// Restrict the ledger to the requested variant
+	return stockLedger(c, database.DB.WithContext(c).Where("variant_id = ?", variantId))
+}
+
+// stockLedger paginates the stock movements selected by db, optionally filtered by the type query parameter
+func stockLedger(c fiber.Ctx, db *gorm.DB) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+
+	if movementType := c.Query("type"); movementType != "" {
+		db = db.Where("type = ?", movementType)
+	}
+
+	result, err := models.Paginate(db.Session(&gorm.Session{}), &models.StockMovement{}, page)
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// AllTags retrieves all product tags ordered by name
+// Typically used for tag pickers and product filters
+func AllTags(c fiber.Ctx) error {
+	var tags []models.Tag
+
+	if err := database.DB.WithContext(c).Order("name").Find(&tags).Error; err != nil {
+		return err
+	}
+
+	return c.JSON(tags)
+}
+
+// CreateTag creates a new product tag
+// Fails with 409 if the name is already taken
+// Request body: see dto.Tag (name)
+func CreateTag(c fiber.Ctx) error {
+	var request dto.Tag
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	tag := models.Tag{
+		Name: request.Name,
+	}
+
+	// Persist new tag to database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tag).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditCreate, models.EntityTags, tag.Id, nil, tag)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(tag)
+}
+
+// GetTag retrieves a specific tag by ID
+// Fails with 404 if the tag does not exist
+// URL parameter: id (tag identifier)
+func GetTag(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var tag models.Tag
+	if err := database.DB.WithContext(c).Where("id = ?", id).First(&tag).Error; err != nil {
+		return lookupError(err, "tag not found")
+	}
+
+	return c.JSON(tag)
+}
+
+// UpdateTag renames an existing tag
+// Fails with 404 if the tag does not exist and with 409 if the name is already taken
+// Request body: see dto.Tag (name)
+// URL parameter: id (tag identifier to update)
+func UpdateTag(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var request dto.Tag
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	var tag models.Tag
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the tag for the audit log
+		if err := tx.Where("id = ?", id).First(&tag).Error; err != nil {
+			return lookupError(err, "tag not found")
+		}
+		before := tag
+
+		tag.Name = request.Name
+		if err := tx.Model(&tag).Update("name", tag.Name).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityTags, tag.Id, before, tag)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(tag)
+}
+
+// DeleteTag permanently removes a tag
+// Cascades deletion to its product_tags links; the tagged products are kept
+// Fails with 404 if the tag does not exist
+// URL parameter: id (tag identifier to delete)
+func DeleteTag(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Where("id = ?", id).First(&tag).Error; err != nil {
+			return lookupError(err, "tag not found")
+		}
+
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityTags, tag.Id, tag, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "tag deleted",
+	})
+}
@@ -2,115 +2,332 @@ package controllers
 
 import (
 	"go-admin/database"
+	"go-admin/dto"
 	"go-admin/middlewares"
 	"go-admin/models"
+	"go-admin/util"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
 // AllUsers retrieves a paginated list of users from the database
-// Requires authorization with "users" permission
+// Only users visible under the caller's row-level policies are returned
 // Uses the generic Paginate function for consistent pagination response format
-// Query parameter: page (defaults to 1 if not provided)
+// Email addresses are masked unless the caller holds users.email:read
+// Query parameters:
+//   - page: page number (defaults to 1 if not provided)
+//   - inactive_days: only return users who have not logged in for at least N days
 func AllUsers(c fiber.Ctx) error {
-	if err := middlewares.IsAuthorized(c, "users"); err != nil {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+
+	db := database.DB.WithContext(c).Scopes(middlewares.PolicyScope(c, models.ResourceUsers, models.ActionRead)).Session(&gorm.Session{})
+	if days, err := strconv.Atoi(c.Query("inactive_days")); err == nil && days > 0 {
+		// Restrict the listing to dormant accounts
+		since := time.Now().AddDate(0, 0, -days)
+		db = db.Scopes(models.InactiveSince(since)).Session(&gorm.Session{})
+	}
+
+	result, err := models.Paginate(db, &models.User{}, page)
+	if err != nil {
+		return err
+	}
+	if err := middlewares.MaskFields(c, models.ResourceUsers, result["data"]); err != nil {
 		return err
 	}
 
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	return c.JSON(models.Paginate(database.DB, &models.User{}, page))
+	return c.JSON(result)
 }
 
-// CreateUser creates a new user account programmatically
-// Requires authorization with "users" permission (admin function)
-// Sets a default password - in production, consider password reset flow
-// Request body should contain: first_name, last_name, email, role_id
+// CreateUser creates a pending user account and sends an invitation email
+// The account cannot log in until the invitee follows the emailed link and sets a password
+// Fails with 403 unless the caller has authority over every requested role
+// (see middlewares.AuthorizeRoleAssignment) and with 409 if the email is already taken
+// Request body: see dto.CreateUser (first_name, last_name, email, region, role_ids)
 func CreateUser(c fiber.Ctx) error {
-	if err := middlewares.IsAuthorized(c, "users"); err != nil {
+	var request dto.CreateUser
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	var user models.User
-
-	// Parse JSON request body
-	if err := c.Bind().Body(&user); err != nil {
+	// Assigning roles must not hand out more access than the caller has
+	target := models.User{Email: request.Email, Region: request.Region}
+	if err := middlewares.AuthorizeRoleAssignment(c, target, request.RoleIds, nil); err != nil {
 		return err
 	}
 
-	// Set default password (should be changed by user on first login)
-	user.SetPassword("3")
+	user := models.User{
+		FirstName: request.FirstName,
+		LastName:  request.LastName,
+		Email:     request.Email,
+		Region:    request.Region,
+		RoleIds:   request.RoleIds,
+	}
+
+	// Identify the inviting admin from the JWT token
+	id, _ := util.ParseJWT(c.Cookies("jwt"))
+	inviterId, _ := strconv.Atoi(id)
+
+	// Invitee chooses their own password when accepting the invitation
+	user.Pending = true
 
-	// Persist new user to database
-	database.DB.Create(&user)
+	// Assign the requested roles
+	user.AssignRoles()
+
+	// Persist user and invitation together; roll back if the email cannot be sent
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Only write user_roles rows - the referenced roles already exist
+		if err := tx.Omit("Roles.*").Create(&user).Error; err != nil {
+			return err
+		}
+
+		if err := recordAudit(tx, c, models.AuditCreate, models.EntityUsers, user.Id, nil, user); err != nil {
+			return err
+		}
+
+		invitation := models.Invitation{
+			UserId:    user.Id,
+			InvitedBy: uint(inviterId),
+		}
+		return issueInvitation(tx, &invitation, user)
+	})
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
 
-// GetUser retrieves a specific user by ID with their role information
-// Requires authorization with "users" permission
+// GetUser retrieves a specific user by ID with all their roles
 // Used for viewing individual user profiles
+// Returns 404 if the user does not exist or is hidden by the caller's row-level policies
+// The email address is masked unless the caller holds users.email:read
 // URL parameter: id (user identifier)
 func GetUser(c fiber.Ctx) error {
-	if err := middlewares.IsAuthorized(c, "users"); err != nil {
-		return err
-	}
-
 	id, _ := strconv.Atoi(c.Params("id"))
 
-	user := models.User{
-		Id: uint(id),
+	var user models.User
+
+	// Find user with preloaded roles, restricted by row-level policies
+	err := database.DB.WithContext(c).Scopes(middlewares.PolicyScope(c, models.ResourceUsers, models.ActionRead)).Preload("Roles").Where("users.id = ?", id).First(&user).Error
+	if err != nil {
+		return lookupError(err, "user not found")
 	}
 
-	// Find user with preloaded role information
-	database.DB.Preload("Role").Find(&user)
+	if err := middlewares.MaskFields(c, models.ResourceUsers, &user); err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
 
 // UpdateUser updates an existing user's information
-// Requires authorization with "users" permission
-// Allows modification of: first_name, last_name, email
+// Allows modification of: first_name, last_name, email, region; omitted fields are left unchanged
+// When role_ids is present, the user's roles are replaced with the given list
+// The email address in the response is masked unless the caller holds users.email:read
+// Request body: see dto.UpdateUser
+// Fails with 403 unless the caller has authority over every role added or removed
+// (see middlewares.AuthorizeRoleAssignment; roles added here are permanent), with 404 if the user does not exist or is hidden
+// by the caller's row-level policies, and with 409 if the email is already taken
 // URL parameter: id (user identifier to update)
 func UpdateUser(c fiber.Ctx) error {
-	if err := middlewares.IsAuthorized(c, "users"); err != nil {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	// Parse and validate updated user data from request body
+	var request dto.UpdateUser
+	if err := dto.Bind(c, &request); err != nil {
 		return err
 	}
 
-	id, _ := strconv.Atoi(c.Params("id"))
-
 	user := models.User{
-		Id: uint(id),
+		Id:      uint(id),
+		RoleIds: request.RoleIds,
+	}
+
+	// Collect the fields that were sent
+	changes := map[string]interface{}{}
+	if request.FirstName != nil {
+		changes["first_name"] = *request.FirstName
+	}
+	if request.LastName != nil {
+		changes["last_name"] = *request.LastName
+	}
+	if request.Email != nil {
+		changes["email"] = *request.Email
+	}
+	if request.Region != nil {
+		changes["region"] = *request.Region
 	}
 
-	// Parse updated user data from request body
-	if err := c.Bind().Body(&user); err != nil {
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the user for the audit log, restricted by row-level policies
+		var before models.User
+		if err := tx.Scopes(middlewares.PolicyScope(c, models.ResourceUsers, models.ActionUpdate)).Preload("Roles").Where("users.id = ?", user.Id).First(&before).Error; err != nil {
+			return lookupError(err, "user not found")
+		}
+
+		// Changing role assignments must not hand out more access than the caller has
+		// Policies of added roles are checked against the attributes the user will have
+		if user.RoleIds != nil {
+			target := before
+			if request.Email != nil {
+				target.Email = *request.Email
+			}
+			if request.Region != nil {
+				target.Region = *request.Region
+			}
+
+			added, removed := changedRoles(before.Roles, user.RoleIds)
+			if err := middlewares.AuthorizeRoleAssignment(c, target, added, nil); err != nil {
+				return err
+			}
+			if err := middlewares.AuthorizeRoleRevocation(c, before, removed); err != nil {
+				return err
+			}
+		}
+
+		// Update user record in database (roles are handled separately below)
+		if len(changes) > 0 {
+			if err := tx.Model(&user).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace role assignments when a role list was provided
+		if user.RoleIds != nil {
+			user.AssignRoles()
+			if err := tx.Model(&user).Omit("Roles.*").Association("Roles").Replace(user.Roles); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Preload("Roles").Where("id = ?", user.Id).First(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityUsers, user.Id, before, user)
+	})
+	if err != nil {
 		return err
 	}
 
-	// Update user record in database
-	database.DB.Model(&user).Updates(user)
+	// Roles may have changed - drop the cached assignment
+	middlewares.InvalidateUser(user.Id)
+
+	if err := middlewares.MaskFields(c, models.ResourceUsers, &user); err != nil {
+		return err
+	}
 
 	return c.JSON(user)
 }
 
+// changedRoles returns the IDs of the roles added and removed when replacing a user's roles with roleIds
+// Roles the user keeps are left out, so resending the current list needs no authority over them
+func changedRoles(current []models.Role, roleIds []uint) (added []uint, removed []uint) {
+	for _, roleId := range roleIds {
+		if !slices.ContainsFunc(current, func(role models.Role) bool { return role.Id == roleId }) && !slices.Contains(added, roleId) {
+			added = append(added, roleId)
+		}
+	}
+	for _, role := range current {
+		if !slices.Contains(roleIds, role.Id) {
+			removed = append(removed, role.Id)
+		}
+	}
+	return added, removed
+}
+
 // DeleteUser permanently removes a user from the database
-// Requires authorization with "users" permission
 // This is a destructive operation - ensure proper authorization is in place
+// When users:delete requires approval, a pending change request is created instead
+// Fails with 404 if the user does not exist or is hidden by the caller's row-level policies
 // URL parameter: id (user identifier to delete)
 func DeleteUser(c fiber.Ctx) error {
-	if err := middlewares.IsAuthorized(c, "users"); err != nil {
+	id, _ := strconv.Atoi(c.Params("id"))
+	return deleteUser(c, uint(id), false)
+}
+
+// deleteUser removes a user, or holds the deletion for approval when it is required
+// approved is set when applying an approved change request
+func deleteUser(c fiber.Ctx, id uint, approved bool) error {
+	if !approved && approvalRequired[models.ChangeDeleteUser] {
+		// Only users the caller can see may be put up for deletion
+		if _, err := visibleUser(c, database.DB.WithContext(c), id, models.ActionDelete); err != nil {
+			return err
+		}
+		return requestApproval(c, models.ChangeDeleteUser, id, nil)
+	}
+
+	var user models.User
+
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the user for the audit log, restricted by row-level policies
+		if err := tx.Scopes(middlewares.PolicyScope(c, models.ResourceUsers, models.ActionDelete)).Preload("Roles").Where("users.id = ?", id).First(&user).Error; err != nil {
+			return lookupError(err, "user not found")
+		}
+
+		// Delete user record from database
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityUsers, user.Id, user, nil)
+	})
+	if err != nil {
 		return err
 	}
 
+	// Drop the cached assignment of the deleted user
+	middlewares.InvalidateUser(id)
+
+	return c.JSON(fiber.Map{
+		"message": "user deleted",
+	})
+}
+
+// GetUserLogins retrieves the paginated login history of a specific user
+// Returns both successful and failed attempts, most recent first
+// Returns 404 if the user does not exist or is hidden by the caller's row-level policies
+// Submitted email addresses are masked unless the caller holds users.email:read
+// URL parameter: id (user identifier)
+// Query parameter: page (defaults to 1 if not provided)
+func GetUserLogins(c fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
+	page, _ := strconv.Atoi(c.Query("page", "1"))
 
-	user := models.User{
-		Id: uint(id),
+	if _, err := visibleUser(c, database.DB.WithContext(c), uint(id), models.ActionRead); err != nil {
+		return err
 	}
 
-	// Delete user record from database
-	database.DB.Delete(&user)
+	// Restrict login events to the requested user
+	db := database.DB.WithContext(c).Where("user_id = ?", id).Session(&gorm.Session{})
 
-	return nil
+	result, err := models.Paginate(db, &models.LoginEvent{}, page)
+	if err != nil {
+		return err
+	}
+	if err := middlewares.MaskFields(c, models.ResourceUsers, result["data"]); err != nil {
+		return err
+	}
+
+	return c.JSON(result)
+}
+
+// visibleUser loads a user if it exists and the caller's row-level policies
+// allow the action (e.g., models.ActionUpdate) on it
+// Only the attributes referenced by policies (id, email, region) are loaded
+// Fails with 404 otherwise, so hidden users cannot be told apart from missing ones
+func visibleUser(c fiber.Ctx, db *gorm.DB, id uint, action string) (models.User, error) {
+	var user models.User
+	err := db.Scopes(middlewares.PolicyScope(c, models.ResourceUsers, action)).
+		Select("users.id", "users.email", "users.region").
+		Where("users.id = ?", id).
+		First(&user).Error
+	if err != nil {
+		return user, lookupError(err, "user not found")
+	}
+	return user, nil
 }
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"go-admin/models"
+	"slices"
+	"testing"
+)
+
+// TestChangedRoles checks which roles replacing a user's roles adds and removes
+// Only those need authority, so resending the current list must change nothing
+func TestChangedRoles(t *testing.T) {
+	current := []models.Role{{Id: 1}, {Id: 2}}
+
+	tests := []struct {
+		name    string
+		roleIds []uint
+		added   []uint
+		removed []uint
+	}{
+		{name: "unchanged", roleIds: []uint{1, 2}},
+		{name: "unchanged in another order", roleIds: []uint{2, 1}},
+		{name: "role added", roleIds: []uint{1, 2, 3}, added: []uint{3}},
+		{name: "role added twice", roleIds: []uint{1, 2, 3, 3}, added: []uint{3}},
+		{name: "role removed", roleIds: []uint{1}, removed: []uint{2}},
+		{name: "role replaced", roleIds: []uint{1, 3}, added: []uint{3}, removed: []uint{2}},
+		{name: "every role removed", roleIds: []uint{}, removed: []uint{1, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, removed := changedRoles(current, test.roleIds)
+			if !slices.Equal(added, test.added) || !slices.Equal(removed, test.removed) {
+				t.Fatalf("changedRoles(%v) = %v, %v, want %v, %v", test.roleIds, added, removed, test.added, test.removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"go-admin/database"
+	"go-admin/dto"
+	"go-admin/metrics"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// AllVariants retrieves all variants of a product with their option values
+// Each variant is returned with its effective price and low-stock flag
+// Fails with 404 if the product does not exist
+// URL parameter: id (product identifier)
+func AllVariants(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	product, err := findVariantProduct(c, id)
+	if err != nil {
+		return err
+	}
+
+	var variants []models.Variant
+	if err := models.WithOptions(database.DB.WithContext(c)).Where("product_id = ?", id).Find(&variants).Error; err != nil {
+		return err
+	}
+	for i := range variants {
+		variants[i].Resolve(product.Price, lowStockThreshold)
+	}
+
+	return c.JSON(variants)
+}
+
+// CreateVariant creates a new variant of a product
+// A positive initial stock is recorded as a receipt in the stock ledger
+// Fails with 404 if the product does not exist, with 409 if the SKU is already taken
+// and with 422 if an option type does not exist or is repeated
+// Request body: see dto.CreateVariant (sku, price, image, stock, options)
+// URL parameter: id (product identifier)
+func CreateVariant(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+
+	var request dto.CreateVariant
+
+	// Parse and validate JSON request body
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	product, err := findVariantProduct(c, id)
+	if err != nil {
+		return err
+	}
+
+	variant := models.Variant{
+		ProductId: product.Id,
+		Sku:       request.Sku,
+		Price:     request.Price,
+		Image:     request.Image,
+		Options:   variantOptions(request.Options),
+	}
+
+	// Persist variant and its option values together with its audit entry
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, models.AuditCreate, models.EntityVariants, variant.Id, nil, variant); err != nil {
+			return err
+		}
+
+		if request.Stock > 0 {
+			if _, err := recordVariantStockMovement(tx, c, product.Id, variant.Id, models.StockReceipt, request.Stock, "initial stock"); err != nil {
+				return err
+			}
+		}
+
+		// Reload the variant with its stock and option types
+		return models.WithOptions(tx).Where("id = ?", variant.Id).First(&variant).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if request.Stock > 0 {
+		metrics.StockMovements.WithLabelValues(models.StockReceipt).Inc()
+	}
+	variant.Resolve(product.Price, lowStockThreshold)
+
+	return c.JSON(variant)
+}
+
+// GetVariant retrieves a specific variant of a product with its option values
+// Fails with 404 if the variant does not exist or belongs to another product
+// URL parameters: id (product identifier), variantId (variant identifier)
+func GetVariant(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	variantId, _ := strconv.Atoi(c.Params("variantId"))
+
+	product, err := findVariantProduct(c, id)
+	if err != nil {
+		return err
+	}
+
+	variant, err := findVariant(c, id, variantId)
+	if err != nil {
+		return err
+	}
+	variant.Resolve(product.Price, lowStockThreshold)
+
+	return c.JSON(variant)
+}
+
+// UpdateVariant updates an existing variant
+// Allows modification of: sku, price and image; omitted fields are left unchanged
+// "price": null removes the price override; when options is present, the variant's
+// option values are replaced with the given list
+// Fails with 404 if the variant does not exist or belongs to another product,
+// with 409 if the SKU is already taken and with 422 for invalid prices or options
+// Request body: see dto.UpdateVariant
+// URL parameters: id (product identifier), variantId (variant identifier)
+func UpdateVariant(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	variantId, _ := strconv.Atoi(c.Params("variantId"))
+
+	// Parse and validate updated variant data from request body
+	var request dto.UpdateVariant
+	if err := dto.Bind(c, &request); err != nil {
+		return err
+	}
+
+	// Collect the fields that were sent
+	changes := map[string]interface{}{}
+	if request.Sku != nil {
+		changes["sku"] = *request.Sku
+	}
+	if request.Image != nil {
+		changes["image"] = *request.Image
+	}
+	if request.Price.Set {
+		// Price override may also be cleared with null
+		if request.Price.Value != nil && *request.Price.Value <= 0 {
+			return util.Invalid("invalid request", fiber.Map{"price": "must be greater than 0"})
+		}
+		changes["price"] = request.Price.Value
+	}
+
+	product, err := findVariantProduct(c, id)
+	if err != nil {
+		return err
+	}
+
+	var variant models.Variant
+	err = database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Snapshot the variant for the audit log
+		var before models.Variant
+		if err := models.WithOptions(tx).Where("id = ? AND product_id = ?", variantId, id).First(&before).Error; err != nil {
+			return lookupError(err, "variant not found")
+		}
+
+		// Update variant record in database (options are handled separately below)
+		// A bare model keeps GORM from writing back the loaded options
+		if len(changes) > 0 {
+			if err := tx.Model(&models.Variant{Id: before.Id}).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace option values when an option list was provided
+		if request.Options != nil {
+			if err := tx.Where("variant_id = ?", before.Id).Delete(&models.VariantOption{}).Error; err != nil {
+				return err
+			}
+			options := variantOptions(request.Options)
+			for i := range options {
+				options[i].VariantId = before.Id
+			}
+			if len(options) > 0 {
+				if err := tx.Create(&options).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := models.WithOptions(tx).Where("id = ?", before.Id).First(&variant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditUpdate, models.EntityVariants, variant.Id, before, variant)
+	})
+	if err != nil {
+		return err
+	}
+	variant.Resolve(product.Price, lowStockThreshold)
+
+	return c.JSON(variant)
+}
+
+// DeleteVariant permanently removes a variant of a product
+// Cascades deletion to its option values; order items that reference the variant
+// keep their title and price but lose the reference
+// Refuses with 409 Conflict once stock movements were recorded for the variant, so the
+// stock ledger keeps its history; fails with 404 if the variant does not exist or belongs to another product
+// URL parameters: id (product identifier), variantId (variant identifier)
+func DeleteVariant(c fiber.Ctx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	variantId, _ := strconv.Atoi(c.Params("variantId"))
+
+	// Delete variant record from database together with its audit entry
+	err := database.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var variant models.Variant
+		if err := models.WithOptions(tx).Where("id = ? AND product_id = ?", variantId, id).First(&variant).Error; err != nil {
+			return lookupError(err, "variant not found")
+		}
+
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, models.AuditDelete, models.EntityVariants, variant.Id, variant, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "variant deleted",
+	})
+}
+
+// findVariantProduct loads the product whose variants are requested
+// Fails with 404 if it does not exist
+func findVariantProduct(c fiber.Ctx, id int) (models.Product, error) {
+	var product models.Product
+	err := database.DB.WithContext(c).Where("id = ?", id).First(&product).Error
+	return product, lookupError(err, "product not found")
+}
+
+// findVariant loads a variant of a product with its option values
+// Fails with 404 if it does not exist or belongs to another product
+func findVariant(c fiber.Ctx, productId int, variantId int) (models.Variant, error) {
+	var variant models.Variant
+	err := models.WithOptions(database.DB.WithContext(c)).Where("id = ? AND product_id = ?", variantId, productId).First(&variant).Error
+	return variant, lookupError(err, "variant not found")
+}
+
+// variantOptions converts the option values of a variant request into VariantOption rows
+// Only the option type IDs are set; the referenced option types already exist
+func variantOptions(options []dto.VariantOption) []models.VariantOption {
+	result := make([]models.VariantOption, len(options))
+	for i, option := range options {
+		result[i] = models.VariantOption{
+			OptionTypeId: option.OptionTypeId,
+			Value:        option.Value,
+		}
+	}
+	return result
+}
@@ -1,10 +1,13 @@
 package database
 
 import (
+	"go-admin/metrics"
 	"go-admin/models"
+	"go-admin/tracing"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // DB holds the global database connection instance
@@ -18,7 +21,10 @@ var DB *gorm.DB
 func Connect() {
 	// Establish connection to MySQL database
 	// Note: Connection credentials are hardcoded - consider using environment variables for production
-	db, err := gorm.Open(mysql.Open("root:fb112358@/go_admin"), &gorm.Config{})
+	// Query errors and slow queries are logged through the structured logger
+	db, err := gorm.Open(mysql.Open("root:fb112358@/go_admin"), &gorm.Config{
+		Logger: queryLogger{level: logger.Warn},
+	})
 
 	if err != nil {
 		panic("failed to connect database")
@@ -27,15 +33,72 @@ func Connect() {
 	// Store connection in global variable for application-wide access
 	DB = db
 
+	// Record query duration, errors and connection pool statistics
+	if err := metrics.InstrumentGORM(db); err != nil {
+		panic("failed to instrument database: " + err.Error())
+	}
+
+	// Create a span for every query, with the SQL recorded without its values
+	if err := tracing.InstrumentGORM(db); err != nil {
+		panic("failed to trace database: " + err.Error())
+	}
+
+	// Use explicit join models so the join tables carry the intended foreign key rules
+	if err := db.SetupJoinTable(&models.User{}, "Roles", &models.UserRole{}); err != nil {
+		panic("failed to set up user_roles: " + err.Error())
+	}
+	if err := db.SetupJoinTable(&models.Role{}, "Permissions", &models.RolePermission{}); err != nil {
+		panic("failed to set up role_permissions: " + err.Error())
+	}
+	if err := db.SetupJoinTable(&models.Product{}, "Tags", &models.ProductTag{}); err != nil {
+		panic("failed to set up product_tags: " + err.Error())
+	}
+
+	// Merge duplicate permission names so the unique index can be created
+	if err := dedupePermissions(db); err != nil {
+		panic("failed to deduplicate permissions: " + err.Error())
+	}
+
 	// Auto-migrate database schema for all models
 	// Creates tables if they don't exist and updates schema for existing tables
-	// Models included: User, Role, Permission, Product, Order, OrderItem
+	// Models included: User, LoginEvent, Invitation, Role, Policy, Permission, Category, Tag, Product, OptionType, Variant, VariantOption, StockMovement, Order, OrderItem, ChangeRequest, AuditLog
 	db.AutoMigrate(
 		&models.User{},
+		&models.LoginEvent{},
+		&models.Invitation{},
 		&models.Role{},
+		&models.Policy{},
 		&models.Permission{},
+		&models.Category{},
+		&models.Tag{},
 		&models.Product{},
+		&models.OptionType{},
+		&models.Variant{},
+		&models.VariantOption{},
+		&models.StockMovement{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.ChangeRequest{},
+		&models.AuditLog{},
 	)
+
+	// Bring ON DELETE rules of foreign keys created by earlier versions up to date
+	if err := migrateForeignKeys(db); err != nil {
+		panic("failed to migrate foreign keys: " + err.Error())
+	}
+
+	// Move single-role assignments into the user_roles join table
+	if err := migrateUserRoles(db); err != nil {
+		panic("failed to migrate user roles: " + err.Error())
+	}
+
+	// Map legacy view_/edit_ permissions onto action-level permissions
+	if err := migrateActionPermissions(db); err != nil {
+		panic("failed to migrate permissions: " + err.Error())
+	}
+
+	// Create field-level permissions for protected fields
+	if err := migrateFieldPermissions(db); err != nil {
+		panic("failed to migrate field permissions: " + err.Error())
+	}
 }
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"go-admin/models"
+
+	"gorm.io/gorm"
+)
+
+// foreignKeyRule describes the ON DELETE behavior a foreign key created by AutoMigrate must have
+type foreignKeyRule struct {
+	model      interface{} // Model owning the relation
+	field      string      // Relation field on the model
+	constraint string      // Constraint name generated by GORM
+	onDelete   string      // Expected ON DELETE rule
+}
+
+// foreignKeyRules lists the foreign keys whose ON DELETE rules were added after their tables
+// AutoMigrate never alters an existing constraint, so these are recreated when they differ
+var foreignKeyRules = []foreignKeyRule{
+	{&models.RolePermission{}, "Role", "fk_role_permissions_role", "CASCADE"},
+	{&models.RolePermission{}, "Permission", "fk_role_permissions_permission", "RESTRICT"},
+	{&models.UserRole{}, "User", "fk_user_roles_user", "CASCADE"},
+	{&models.UserRole{}, "Role", "fk_user_roles_role", "RESTRICT"},
+	{&models.Invitation{}, "User", "fk_invitations_user", "CASCADE"},
+	{&models.StockMovement{}, "Product", "fk_stock_movements_product", "RESTRICT"},
+	{&models.StockMovement{}, "Variant", "fk_stock_movements_variant", "RESTRICT"},
+	{&models.ChangeRequest{}, "Requester", "fk_change_requests_requester", "SET NULL"},
+}
+
+// migrateForeignKeys recreates foreign keys whose ON DELETE rule does not match the models
+// Reads the current rules from information_schema, so constraints that are already correct are left alone
+func migrateForeignKeys(db *gorm.DB) error {
+	migrator := db.Migrator()
+
+	for _, rule := range foreignKeyRules {
+		var current string
+		err := db.Raw(`
+			SELECT DELETE_RULE FROM information_schema.REFERENTIAL_CONSTRAINTS
+			WHERE CONSTRAINT_SCHEMA = DATABASE() AND CONSTRAINT_NAME = ?
+			`, rule.constraint).Scan(&current).Error
+		if err != nil {
+			return err
+		}
+
+		// MySQL reports the implicit default as "RESTRICT" or "NO ACTION"
+		if current == rule.onDelete || (rule.onDelete == "RESTRICT" && current == "NO ACTION") {
+			continue
+		}
+
+		if current != "" {
+			if err := migrator.DropConstraint(rule.model, rule.constraint); err != nil {
+				return err
+			}
+		}
+		if err := migrator.CreateConstraint(rule.model, rule.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
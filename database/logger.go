@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-admin/util"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which a query is logged as slow
+var slowQueryThreshold = util.GetenvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+
+// queryLogger writes GORM errors and slow queries through util.Log
+// Queries issued with DB.WithContext(c) inside a handler are logged with the request ID
+// SQL is logged with placeholders only, so values such as password hashes and tokens never reach the log
+type queryLogger struct {
+	level logger.LogLevel
+}
+
+// LogMode implements logger.Interface
+func (l queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.level = level
+	return l
+}
+
+// Info implements logger.Interface
+func (l queryLogger) Info(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= logger.Info {
+		util.Log(ctx).Info(fmt.Sprintf(message, args...))
+	}
+}
+
+// Warn implements logger.Interface
+func (l queryLogger) Warn(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		util.Log(ctx).Warn(fmt.Sprintf(message, args...))
+	}
+}
+
+// Error implements logger.Interface
+func (l queryLogger) Error(ctx context.Context, message string, args ...interface{}) {
+	if l.level >= logger.Error {
+		util.Log(ctx).Error(fmt.Sprintf(message, args...))
+	}
+}
+
+// Trace implements logger.Interface
+// Logs failed queries (except record-not-found) as errors and slow queries as warnings
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		util.Log(ctx).Error("database query failed", "error", err, "sql", sql, "rows", rows, "elapsed", elapsed)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		util.Log(ctx).Warn("slow database query", "sql", sql, "rows", rows, "elapsed", elapsed)
+	}
+}
+
+// ParamsFilter implements gorm's ParamsFilter interface
+// Dropping the parameters makes GORM render SQL with placeholders instead of values
+func (l queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
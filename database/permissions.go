@@ -0,0 +1,127 @@
+package database
+
+import (
+	"go-admin/models"
+
+	"gorm.io/gorm"
+)
+
+// migrateActionPermissions maps legacy "view_<resource>"/"edit_<resource>" permissions
+// onto action-level "<resource>:<action>" permissions
+// Creates any missing action permissions and grants them to every role holding the legacy one
+// Legacy permissions are left in place so existing role assignments keep working during the transition
+// Safe to run on every startup - existing permissions and grants are not duplicated
+func migrateActionPermissions(db *gorm.DB) error {
+	var legacy []models.Permission
+	if err := db.Where("name LIKE ? OR name LIKE ?", "view\\_%", "edit\\_%").Find(&legacy).Error; err != nil {
+		return err
+	}
+
+	for _, permission := range legacy {
+		// Find roles currently granted the legacy permission
+		var roles []models.Role
+		err := db.Joins("JOIN role_permissions rp ON rp.role_id = roles.id").
+			Where("rp.permission_id = ?", permission.Id).
+			Find(&roles).Error
+		if err != nil {
+			return err
+		}
+
+		for _, name := range models.ActionPermissionNames(permission.Name) {
+			// Create the action permission if it does not exist yet
+			action := models.Permission{}
+			if err := db.Where(models.Permission{Name: name}).FirstOrCreate(&action).Error; err != nil {
+				return err
+			}
+
+			// Grant it to every role that held the legacy permission
+			for i := range roles {
+				if err := db.Model(&roles[i]).Association("Permissions").Append(&action); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateFieldPermissions creates the field-level read permissions of models.ProtectedFields
+// When a field permission is created, it is granted to every role holding "<resource>:update"
+// or "edit_<resource>", so administrators keep seeing fields that become protected
+// Existing field permissions are left alone, so revoking one from a role is not undone on restart
+func migrateFieldPermissions(db *gorm.DB) error {
+	for resource, fields := range models.ProtectedFields {
+		for _, field := range fields {
+			name := models.FieldPermissionName(resource, field, models.ActionRead)
+
+			var existing int64
+			if err := db.Model(&models.Permission{}).Where("name = ?", name).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+
+			permission := models.Permission{Name: name}
+			if err := db.Create(&permission).Error; err != nil {
+				return err
+			}
+
+			// Grant it to roles that can already modify the resource
+			err := db.Exec(`
+				INSERT IGNORE INTO role_permissions (role_id, permission_id)
+				SELECT DISTINCT rp.role_id, ? FROM role_permissions rp
+				JOIN permissions p ON p.id = rp.permission_id
+				WHERE p.name IN ?
+				`, permission.Id, []string{models.PermissionName(resource, models.ActionUpdate), "edit_" + resource}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dedupePermissions merges permissions that share the same name into the one with the lowest ID
+// Role grants of the duplicates are moved to the kept permission before the duplicates are deleted
+// Must run before AutoMigrate so the unique index on permissions.name can be created
+func dedupePermissions(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Permission{}) {
+		return nil
+	}
+
+	var duplicates []struct {
+		Name string
+		Keep uint
+	}
+	err := db.Model(&models.Permission{}).
+		Select("name, MIN(id) AS keep").
+		Group("name").
+		Having("COUNT(*) > 1").
+		Scan(&duplicates).Error
+	if err != nil {
+		return err
+	}
+
+	for _, duplicate := range duplicates {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Model(&models.Permission{}).Where("name = ? AND id <> ?", duplicate.Name, duplicate.Keep).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+
+			// Move grants to the kept permission, skipping roles that already hold it
+			if err := tx.Exec("INSERT IGNORE INTO role_permissions (role_id, permission_id) SELECT role_id, ? FROM role_permissions WHERE permission_id IN ?", duplicate.Keep, ids).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM role_permissions WHERE permission_id IN ?", ids).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.Permission{}, ids).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// migrateUserRoles moves single-role assignments from the legacy users.role_id column
+// into the user_roles join table, then drops the column
+// Does nothing once the column is gone, so it is safe to run on every startup
+func migrateUserRoles(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn("users", "role_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Copy every existing assignment that is not already present in user_roles
+		err := tx.Exec(`
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT u.id, u.role_id FROM users u
+			WHERE u.role_id IS NOT NULL AND u.role_id > 0
+			AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role_id = u.role_id)
+			`).Error
+		if err != nil {
+			return err
+		}
+
+		// Remove the foreign key created for the old belongs-to association before dropping the column
+		if migrator.HasConstraint("users", "fk_users_role") {
+			if err := migrator.DropConstraint("users", "fk_users_role"); err != nil {
+				return err
+			}
+		}
+		return migrator.DropColumn("users", "role_id")
+	})
+}
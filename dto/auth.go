@@ -0,0 +1,36 @@
+package dto
+
+// Register is the request body of POST /api/register
+type Register struct {
+	FirstName       string `json:"first_name" validate:"required,max=255"`
+	LastName        string `json:"last_name" validate:"required,max=255"`
+	Email           string `json:"email" validate:"required,email,max=191"`
+	Password        string `json:"password" validate:"required,min=8,max=72"`
+	PasswordConfirm string `json:"password_confirm" validate:"required,eqfield=Password"`
+}
+
+// Login is the request body of POST /api/login
+type Login struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// UpdateInfo is the request body of PUT /api/users/info
+type UpdateInfo struct {
+	FirstName string `json:"first_name" validate:"required,max=255"`
+	LastName  string `json:"last_name" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required,email,max=191"`
+}
+
+// UpdatePassword is the request body of PUT /api/users/password
+type UpdatePassword struct {
+	Password        string `json:"password" validate:"required,min=8,max=72"`
+	PasswordConfirm string `json:"password_confirm" validate:"required,eqfield=Password"`
+}
+
+// AcceptInvitation is the request body of POST /api/invitations/accept
+type AcceptInvitation struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required,min=8,max=72"`
+	PasswordConfirm string `json:"password_confirm" validate:"required,eqfield=Password"`
+}
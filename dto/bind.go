@@ -0,0 +1,259 @@
+package dto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"go-admin/database"
+	"go-admin/util"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+)
+
+// validate checks request structs against their `validate` tags
+// Field errors are reported under the JSON names of the fields
+// Besides the built-in rules, `exists=<table>` checks that an ID (or every ID of a list)
+// refers to an existing row of table
+var validate = newValidator()
+
+// newValidator builds the request validator with the custom rules registered
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON name (e.g., "first_name" instead of "FirstName")
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidationCtx("exists", exists)
+	return v
+}
+
+// Bind decodes the JSON request body into out and validates it
+// Fails with:
+//   - 400 Bad Request if the body is empty or not valid JSON
+//   - 422 Unprocessable Entity if it contains unknown fields, values of the wrong type,
+//     or values rejected by the `validate` tags of out; details maps each field to the reason
+//
+// Usage: var request dto.CreateProduct; if err := dto.Bind(c, &request); err != nil { return err }
+func Bind(c fiber.Ctx, out interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(c.Body()))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(out); err != nil {
+		return decodeError(err)
+	}
+	if decoder.More() {
+		return util.BadRequest("request body must contain a single JSON object")
+	}
+
+	return Validate(c, out)
+}
+
+// Validate checks a request struct against its `validate` tags
+// ctx is used by rules that query the database, so pass the fiber.Ctx of the request
+func Validate(ctx context.Context, out interface{}) error {
+	err := validate.StructCtx(ctx, out)
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	details := fiber.Map{}
+	for _, fieldError := range fieldErrors {
+		details[fieldName(fieldError)] = fieldMessage(fieldError)
+	}
+	return util.Invalid("invalid request", details)
+}
+
+// decodeError maps a JSON decoding error onto a 400 or 422 application error
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return util.BadRequest("request body is required")
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		// Raised by a custom decoder (e.g., ID), which does not know the field name
+		return util.Invalid("invalid request: "+typeErr.Value+" must be a "+typeName(typeErr.Type), nil)
+	case errors.As(err, &typeErr):
+		return util.Invalid("invalid request", fiber.Map{typeErr.Field: "must be a " + typeName(typeErr.Type)})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return util.Invalid("invalid request", fiber.Map{field: "is not allowed"})
+	default:
+		return util.BadRequest("malformed JSON")
+	}
+}
+
+// typeName describes the JSON type expected for a Go type
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// fieldName returns the JSON path of an invalid field (e.g., "email", "policies[0].field")
+func fieldName(fieldError validator.FieldError) string {
+	// Namespace is prefixed with the request struct name (e.g., "CreateUser.email")
+	_, name, _ := strings.Cut(fieldError.Namespace(), ".")
+	return name
+}
+
+// fieldMessage describes why a field failed validation
+func fieldMessage(fieldError validator.FieldError) string {
+	param := fieldError.Param()
+
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fieldError.Kind() == reflect.String && param == "1" {
+			return "must not be empty"
+		}
+		if fieldError.Kind() == reflect.String {
+			return "must be at least " + param + " characters long"
+		}
+		if fieldError.Kind() == reflect.Slice {
+			return "must contain at least " + param + " item(s)"
+		}
+		return "must be at least " + param
+	case "max":
+		if fieldError.Kind() == reflect.String {
+			return "must be at most " + param + " characters long"
+		}
+		return "must be at most " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "eqfield":
+		return "must match " + jsonFieldName(param)
+	case "gtfield":
+		return "must be after " + jsonFieldName(param)
+	case "unique":
+		return "must not repeat " + jsonFieldName(param)
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(param, " ", ", ")
+	case "exists":
+		return "refers to a missing record"
+	default:
+		return "is invalid"
+	}
+}
+
+// jsonFieldName returns the JSON name of a sibling field referenced by a rule
+// (e.g., eqfield=PasswordConfirm -> "password_confirm")
+func jsonFieldName(goName string) string {
+	var builder strings.Builder
+	for i, r := range goName {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// exists implements the `exists=<table>` rule
+// Accepts an unsigned ID or a list of them; zero IDs are left to other rules (e.g., gt=0)
+func exists(ctx context.Context, field validator.FieldLevel) bool {
+	ids := map[uint64]bool{}
+
+	value := field.Field()
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if id := value.Index(i).Uint(); id > 0 {
+				ids[id] = true
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if id := value.Uint(); id > 0 {
+			ids[id] = true
+		}
+	default:
+		return false
+	}
+
+	if len(ids) == 0 {
+		return true
+	}
+
+	list := make([]uint64, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+
+	var count int64
+	err := database.DB.WithContext(ctx).Table(field.Param()).Where("id IN ?", list).Count(&count).Error
+	if err != nil {
+		util.Log(ctx).Error("existence check failed", "table", field.Param(), "error", err)
+		return false
+	}
+	return count == int64(len(list))
+}
+
+// ID is a record ID accepted as a JSON number or a numeric string (e.g., 3 or "3")
+// The frontend sends permission IDs as strings, so both forms are supported
+type ID uint
+
+// UnmarshalJSON implements json.Unmarshaler for ID
+func (id *ID) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+
+	value, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeFor[ID]()}
+	}
+	*id = ID(value)
+	return nil
+}
+
+// Optional wraps a request field whose presence matters, such as a field that may be
+// explicitly set to null to clear it; Set reports whether the field was sent at all
+// Use with the omitzero JSON option so an unset field is also left out when re-encoded
+type Optional[T any] struct {
+	Set   bool
+	Value T
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Optional
+// Called for every field present in the body, including an explicit null
+func (optional *Optional[T]) UnmarshalJSON(data []byte) error {
+	optional.Set = true
+	return json.Unmarshal(data, &optional.Value)
+}
+
+// MarshalJSON implements json.Marshaler for Optional
+func (optional Optional[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(optional.Value)
+}
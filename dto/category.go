@@ -0,0 +1,14 @@
+package dto
+
+// Category is the request body of POST /api/categories and PUT /api/categories/:id
+// parent_id is optional; on update, omitting it leaves the category in place,
+// "parent_id": null moves it to the root level and an ID moves it below that category
+type Category struct {
+	Name     string        `json:"name" validate:"required,max=191"`
+	ParentId Optional[*ID] `json:"parent_id,omitzero"`
+}
+
+// Tag is the request body of POST /api/tags and PUT /api/tags/:id
+type Tag struct {
+	Name string `json:"name" validate:"required,max=191"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+// CreateProduct is the request body of POST /api/products
+// A positive stock is recorded as the product's first receipt
+type CreateProduct struct {
+	Title       string  `json:"title" validate:"required,max=255"`
+	Description string  `json:"description"`
+	Image       string  `json:"image" validate:"max=255"`
+	Price       float64 `json:"price" validate:"gt=0"`
+	Stock       int     `json:"stock" validate:"gte=0"`
+	CategoryId  *uint   `json:"category_id" validate:"omitempty,gt=0,exists=categories"`
+	TagIds      []uint  `json:"tag_ids" validate:"omitempty,exists=tags,dive,gt=0"`
+}
+
+// UpdateProduct is the request body of PUT /api/products/:id
+// Omitted fields are left unchanged; stock is changed through AdjustStock instead
+// "category_id": null removes the product from its category; tag_ids, when sent, replaces its tags
+type UpdateProduct struct {
+	Title       *string         `json:"title" validate:"omitempty,min=1,max=255"`
+	Description *string         `json:"description"`
+	Image       *string         `json:"image" validate:"omitempty,max=255"`
+	Price       *float64        `json:"price" validate:"omitempty,gt=0"`
+	CategoryId  Optional[*uint] `json:"category_id,omitzero"`
+	TagIds      []uint          `json:"tag_ids" validate:"omitempty,exists=tags,dive,gt=0"`
+}
+
+// AdjustStock is the request body of POST /api/products/:id/stock
+// quantity is positive for receipts, sales and returns, and signed for adjustments
+type AdjustStock struct {
+	Type     string `json:"type" validate:"required,oneof=receipt sale adjustment return"`
+	Quantity int    `json:"quantity" validate:"required"`
+	Reason   string `json:"reason" validate:"max=255"`
+}
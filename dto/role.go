@@ -0,0 +1,30 @@
+package dto
+
+// Role is the request body of POST /api/roles and PUT /api/roles/:id
+// Permission IDs may be sent as numbers or numeric strings
+// parent_id and policies are optional; on update, omitting them leaves them unchanged,
+// "parent_id": null removes the parent and a policy list replaces the role's policies
+type Role struct {
+	Name        string                 `json:"name" validate:"required,max=191"`
+	Permissions []ID                   `json:"permissions" validate:"required,exists=permissions,dive,gt=0"`
+	ParentId    Optional[*ID]          `json:"parent_id,omitzero"`
+	Policies    Optional[[]RolePolicy] `json:"policies,omitzero"`
+}
+
+// RolePolicy is a row-level policy of a Role request
+// Resources, fields and user attributes are checked against the whitelist by models.Policy.Validate
+type RolePolicy struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Value    string `json:"value"`
+}
+
+// Permission is the request body of POST /api/permissions and PUT /api/permissions/:id
+type Permission struct {
+	Name string `json:"name" validate:"required,max=191"`
+}
+
+// RejectChange is the optional request body of POST /api/approvals/:id/reject
+type RejectChange struct {
+	Reason string `json:"reason" validate:"max=1000"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// CreateUser is the request body of POST /api/users
+type CreateUser struct {
+	FirstName string `json:"first_name" validate:"required,max=255"`
+	LastName  string `json:"last_name" validate:"required,max=255"`
+	Email     string `json:"email" validate:"required,email,max=191"`
+	Region    string `json:"region" validate:"max=191"`
+	RoleIds   []uint `json:"role_ids" validate:"required,min=1,exists=roles,dive,gt=0"`
+}
+
+// UpdateUser is the request body of PUT /api/users/:id
+// Omitted fields are left unchanged; role_ids, when sent, replaces the user's roles
+type UpdateUser struct {
+	FirstName *string `json:"first_name" validate:"omitempty,min=1,max=255"`
+	LastName  *string `json:"last_name" validate:"omitempty,min=1,max=255"`
+	Email     *string `json:"email" validate:"omitempty,email,max=191"`
+	Region    *string `json:"region" validate:"omitempty,max=191"`
+	RoleIds   []uint  `json:"role_ids" validate:"omitempty,exists=roles,dive,gt=0"`
+}
+
+// GrantRole is the request body of POST /api/users/:id/roles
+// Either bound may be omitted; expires_at must be in the future and after starts_at
+type GrantRole struct {
+	RoleId    uint       `json:"role_id" validate:"required,exists=roles"`
+	StartsAt  *time.Time `json:"starts_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+// OptionType is the request body of POST /api/option-types and PUT /api/option-types/:id
+type OptionType struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+// CreateVariant is the request body of POST /api/products/:id/variants
+// price overrides the product price when sent; a positive stock is recorded as the variant's first receipt
+type CreateVariant struct {
+	Sku     string          `json:"sku" validate:"required,max=64"`
+	Price   *float64        `json:"price" validate:"omitempty,gt=0"`
+	Image   string          `json:"image" validate:"max=255"`
+	Stock   int             `json:"stock" validate:"gte=0"`
+	Options []VariantOption `json:"options" validate:"unique=OptionTypeId,dive"`
+}
+
+// UpdateVariant is the request body of PUT /api/products/:id/variants/:variantId
+// Omitted fields are left unchanged; "price": null removes the price override, and an
+// option list replaces the variant's options. Stock is changed through AdjustStock instead
+type UpdateVariant struct {
+	Sku     *string            `json:"sku" validate:"omitempty,min=1,max=64"`
+	Price   Optional[*float64] `json:"price,omitzero"`
+	Image   *string            `json:"image" validate:"omitempty,max=255"`
+	Options []VariantOption    `json:"options" validate:"omitempty,unique=OptionTypeId,dive"`
+}
+
+// VariantOption is an option value of a variant request (e.g., { "option_type_id": 1, "value": "M" })
+type VariantOption struct {
+	OptionTypeId uint   `json:"option_type_id" validate:"required,exists=option_types"`
+	Value        string `json:"value" validate:"required,max=191"`
+}
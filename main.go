@@ -1,19 +1,73 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"go-admin/controllers"
 	"go-admin/database"
+	"go-admin/metrics"
+	"go-admin/middlewares"
+	"go-admin/rbac"
 	"go-admin/routes"
+	"go-admin/tracing"
+	"go-admin/util"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 )
 
 func main() {
+	// Export request, permission check and query spans when configured
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		panic("failed to set up tracing: " + err.Error())
+	}
+	// Flushes buffered spans when main returns, after the server has shut down
+	defer shutdownTracing(context.Background())
+
 	// Establish database connection
 	database.Connect()
 
+	// Command line mode: go-admin rbac apply [--file rbac.yaml] [--dry-run]
+	if len(os.Args) > 1 && os.Args[1] == "rbac" {
+		if err := rbac.Command(database.DB, os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			shutdownTracing(context.Background())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Sync roles and permissions from the policy file when one is configured
+	if path := os.Getenv("RBAC_POLICY_FILE"); path != "" {
+		if err := rbac.Sync(database.DB, path, false, os.Stdout); err != nil {
+			panic("failed to apply RBAC policy: " + err.Error())
+		}
+	}
+
+	// Refuse to start when an action cannot be held for approval as configured
+	if err := controllers.ValidateApprovalActions(); err != nil {
+		panic("invalid approval configuration: " + err.Error())
+	}
+
 	// Create a new Fiber application instance
-	app := fiber.New()
+	// Errors returned by handlers are rendered as a uniform JSON envelope
+	app := fiber.New(fiber.Config{
+		ErrorHandler: middlewares.ErrorHandler,
+	})
+
+	// Start a span for every request, continuing incoming W3C trace context
+	app.Use(middlewares.Tracing)
+
+	// Assign request IDs and log every request
+	app.Use(middlewares.RequestLogger)
+
+	// Record request counts and latency per route
+	app.Use(middlewares.Metrics)
 
 	// Configure Cross-Origin Resource Sharing (CORS) middleware
 	app.Use(cors.New(cors.Config{
@@ -28,6 +82,49 @@ func main() {
 	// This sets up all API endpoints(routes) and their corresponding handlers
 	routes.Setup(app)
 
+	// Create the permissions declared by routes that are missing from the database
+	created, err := middlewares.SyncPermissions(database.DB)
+	if err != nil {
+		panic("failed to create route permissions: " + err.Error())
+	}
+	for _, name := range created {
+		util.Logger.Info("created permission required by routes", "permission", name)
+	}
+
+	// Remove expired time-bound role grants and warn their grantors ahead of expiry
+	rbac.StartGrantSweeper(database.DB,
+		util.GetenvDuration("ROLE_GRANT_SWEEP_INTERVAL", time.Minute),
+		util.GetenvDuration("ROLE_GRANT_EXPIRY_NOTICE", 24*time.Hour))
+
+	// Serve metrics on a separate admin port when configured (e.g., METRICS_ADDR=:9100)
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			if err := metrics.Serve(addr); err != nil {
+				util.Logger.Error("metrics listener stopped", "addr", addr, "error", err)
+			}
+		}()
+	}
+
+	// Shut down gracefully on SIGINT/SIGTERM: stop accepting connections and let
+	// in-flight requests finish for up to SHUTDOWN_TIMEOUT (default 10s)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		util.Logger.Info("shutting down")
+		if err := app.ShutdownWithTimeout(util.GetenvDuration("SHUTDOWN_TIMEOUT", 10*time.Second)); err != nil {
+			util.Logger.Error("graceful shutdown failed", "error", err)
+		}
+	}()
+
 	// Start the HTTP server and listen on port 8000
-	app.Listen(":8000")
+	// Listen returns once the server stops accepting connections
+	if err := app.Listen(":8000"); err != nil {
+		util.Logger.Error("server stopped", "error", err)
+	}
+
+	// Wait for in-flight requests to finish before buffered spans are flushed
+	stop()
+	<-shutdown
 }
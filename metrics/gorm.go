@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startKey stores the start time of a query on the GORM statement
+const startKey = "metrics:start"
+
+// InstrumentGORM registers callbacks recording the duration and errors of every query by
+// table and operation, and exports the connection pool statistics of db
+func InstrumentGORM(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	Registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, namespace))
+
+	callbacks := db.Callback()
+	registrations := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, registration := range registrations {
+		if err := registration.before("metrics:before_"+registration.operation, startQuery); err != nil {
+			return err
+		}
+		if err := registration.after("metrics:after_"+registration.operation, observeQuery(registration.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQuery records the start time of a query
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// observeQuery returns a callback recording the duration and outcome of a query
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		QueryDuration.WithLabelValues(table, operation).Observe(time.Since(value.(time.Time)).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			QueryErrors.WithLabelValues(table, operation).Inc()
+		}
+	}
+}
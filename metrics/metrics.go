@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// namespace prefixes every application metric
+const namespace = "goadmin"
+
+// Registry holds every metric exposed on /metrics
+// A dedicated registry keeps the exposition limited to what the application registers
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled by route pattern (e.g., "/api/users/:id") rather than raw path
+var (
+	// HttpRequests counts handled requests by method, route and status code
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// HttpDuration observes request latency by method and route
+	HttpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Database metrics, recorded by the GORM callbacks registered with InstrumentGORM
+var (
+	// QueryDuration observes query latency by table and operation (create, query, update, delete, row, raw)
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency in seconds.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"table", "operation"})
+
+	// QueryErrors counts failed queries by table and operation (record-not-found is not an error)
+	QueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Number of failed database queries.",
+	}, []string{"table", "operation"})
+)
+
+// Business metrics
+var (
+	// Logins counts login attempts by result ("success" or "failure")
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+
+	// OrdersExported counts orders written to CSV exports
+	OrdersExported = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_exported_total",
+		Help:      "Number of orders written to CSV exports.",
+	})
+
+	// StockMovements counts recorded stock movements by type ("receipt", "sale", "adjustment" or "return")
+	StockMovements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stock_movements_total",
+		Help:      "Number of recorded stock movements by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequests,
+		HttpDuration,
+		QueryDuration,
+		QueryErrors,
+		Logins,
+		OrdersExported,
+		StockMovements,
+	)
+}
@@ -1,9 +1,11 @@
 package middlewares
 
 import (
+	"go-admin/tracing"
 	"go-admin/util"
 
 	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // IsAuthenticated validates JWT token presence and authenticity
@@ -17,11 +19,15 @@ func IsAuthenticated(c fiber.Ctx) error {
 	cookie := c.Cookies("jwt")
 
 	// Validate token using utility function
-	if _, err := util.ParseJWT(cookie); err != nil {
-		c.Status(fiber.StatusUnauthorized)
-		return c.JSON(fiber.Map{
-			"message": "unauthorized",
-		})
+	_, span := tracing.Start(c, "auth.authenticate")
+	_, err := util.ParseJWT(cookie)
+	if err != nil {
+		span.SetStatus(codes.Error, "unauthorized")
+	}
+	span.End()
+
+	if err != nil {
+		return util.Unauthorized()
 	}
 
 	// Token is valid - proceed to next handler
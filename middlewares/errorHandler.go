@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"errors"
+	"go-admin/util"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// MySQL error numbers mapped onto client errors
+const (
+	mysqlDuplicateEntry     = 1062 // Unique index violation
+	mysqlRowIsReferenced    = 1451 // Delete/update of a row other rows still reference
+	mysqlNoReferencedRow    = 1452 // Insert/update referencing a missing row
+	mysqlNoReferencedRowOld = 1216 // Same as 1452, reported by older servers
+)
+
+// duplicateKeyPattern extracts the index name from a duplicate entry message
+// e.g. "Duplicate entry 'a@b.c' for key 'users.email'" -> "users.email"
+var duplicateKeyPattern = regexp.MustCompile(`for key '([^']+)'`)
+
+// ErrorHandler renders every error returned by a handler or middleware as
+// { "code", "message", "details", "request_id" } with the status ResolveError maps it to
+// Registered as the application's ErrorHandler in main.go; RequestLogger invokes it so
+// the logged status matches the response, and logs the cause of 5xx errors
+func ErrorHandler(c fiber.Ctx, err error) error {
+	appErr := ResolveError(err)
+
+	c.Status(appErr.Code)
+	return c.JSON(fiber.Map{
+		"code":       appErr.Code,
+		"message":    appErr.Message,
+		"details":    appErr.Details,
+		"request_id": c.Locals(util.RequestIdKey),
+	})
+}
+
+// ResolveError maps an error onto the application error describing the response to send
+//   - *util.Error: returned as is
+//   - *fiber.Error (e.g., malformed request bodies, unknown routes): its status and message
+//   - *PermissionError: 403 Forbidden naming the missing permission in details
+//   - gorm.ErrRecordNotFound: 404 Not Found
+//   - unique index violations: 409 Conflict naming the field in details
+//   - foreign key violations: 409 Conflict when the row is still referenced,
+//     422 Unprocessable Entity when it references a missing row
+//   - anything else: 500 Internal Server Error without further information
+func ResolveError(err error) *util.Error {
+	var appErr *util.Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return util.NewError(fiberErr.Code, fiberErr.Message)
+	}
+
+	var permissionErr *PermissionError
+	if errors.As(err, &permissionErr) {
+		return util.Forbidden("forbidden").WithDetails(fiber.Map{"permission": permissionErr.Permission})
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return util.NotFound("record not found")
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return util.Conflict("record already exists").WithDetails(fiber.Map{"field": duplicateField(mysqlErr.Message)})
+		case mysqlRowIsReferenced:
+			return util.Conflict("record is still referenced by other records")
+		case mysqlNoReferencedRow, mysqlNoReferencedRowOld:
+			return util.Invalid("record references a missing record", nil)
+		}
+	}
+
+	return util.Internal()
+}
+
+// duplicateField returns the column named by a duplicate entry message (e.g., "email")
+// Only the index name is used; the duplicated value is never echoed back
+func duplicateField(message string) string {
+	match := duplicateKeyPattern.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+
+	// MySQL 8 qualifies the index with its table ("users.email"); GORM names
+	// single-column unique indexes after the column or "idx_<table>_<column>"
+	key := match[1]
+	if table, name, ok := strings.Cut(key, "."); ok {
+		key = strings.TrimPrefix(name, "idx_"+table+"_")
+	}
+	return key
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"go-admin/models"
+	"go-admin/util"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ReadableFields returns a function reporting whether the authenticated user may read
+// a protected field of a resource, i.e. holds "<resource>.<field>:read"
+// Fields not listed in models.ProtectedFields are always readable
+// Returns the database error if the user's permissions could not be loaded
+func ReadableFields(c fiber.Ctx, resource string) (func(field string) bool, error) {
+	id, _ := util.ParseJWT(c.Cookies("jwt"))
+	userId, _ := strconv.Atoi(id)
+	permissions, err := cache.resolve(c, uint(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(field string) bool {
+		protected := slices.Contains(models.ProtectedFields[resource], field)
+		return !protected || permissions[models.FieldPermissionName(resource, field, models.ActionRead)]
+	}, nil
+}
+
+// MaskFields masks the protected fields the authenticated user may not read
+// data may be a pointer to a models.FieldMasker or a slice of FieldMasker values or pointers,
+// as returned by Entity.Take; other values are left untouched
+// Returns the database error if the user's permissions could not be loaded; data must not be sent then
+// Usage: err := middlewares.MaskFields(c, "users", result["data"]) with result returned by models.Paginate
+func MaskFields(c fiber.Ctx, resource string, data interface{}) error {
+	readable, err := ReadableFields(c, resource)
+	if err != nil {
+		return err
+	}
+
+	if record, ok := data.(models.FieldMasker); ok {
+		record.MaskFields(readable)
+		return nil
+	}
+
+	// Slice elements are addressable, so value slices can be masked in place
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice {
+		return nil
+	}
+	for i := 0; i < value.Len(); i++ {
+		element := value.Index(i)
+		if element.Kind() != reflect.Pointer {
+			element = element.Addr()
+		}
+		if record, ok := element.Interface().(models.FieldMasker); ok {
+			record.MaskFields(readable)
+		}
+	}
+	return nil
+}
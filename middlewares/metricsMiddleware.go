@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"go-admin/metrics"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Metrics records the count and latency of every request by route pattern
+// Requests whose handler returns an error are counted with the status the error maps to
+// Usage: app.Use(middlewares.Metrics) right after middlewares.RequestLogger
+func Metrics(c fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = ResolveError(err).Code
+	}
+
+	route := c.Route().Path
+	metrics.HttpRequests.WithLabelValues(c.Method(), route, strconv.Itoa(status)).Inc()
+	metrics.HttpDuration.WithLabelValues(c.Method(), route).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// metricsToken is the bearer token Prometheus presents when scraping the main port
+// Configured with METRICS_TOKEN; when empty, only users holding metrics:read can read /metrics
+var metricsToken = util.Getenv("METRICS_TOKEN", "")
+
+// RequireMetricsAccess protects /metrics on the main port
+// Accepts either "Authorization: Bearer <METRICS_TOKEN>" (for scrapers) or a logged-in user
+// holding the metrics:read permission (for people)
+func RequireMetricsAccess(c fiber.Ctx) error {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if ok && metricsToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(metricsToken)) == 1 {
+		return c.Next()
+	}
+
+	return RequireAction(models.ResourceMetrics, models.ActionRead)(c)
+}
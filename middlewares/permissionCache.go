@@ -0,0 +1,225 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/util"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// permissionCache keeps resolved user role assignments and role permission sets in memory
+// so IsAuthorized does not hit the database on every request
+// Entries are invalidated explicitly when roles, users or permissions change
+// and additionally expire after PERMISSION_CACHE_TTL (default 5m) as a safety net
+type permissionCache struct {
+	mu         sync.RWMutex
+	generation uint64              // Incremented on every invalidation to discard in-flight lookups
+	users      map[uint]cachedUser // User ID -> assigned roles
+	roles      map[uint]cachedRole // Role ID -> permission names and policies
+	ttl        time.Duration       // Lifetime of a cache entry
+}
+
+// cachedUser is a cache entry mapping a user to their role assignments and policy attributes
+// Assignments are cached with their validity window and filtered on every lookup,
+// so time-bound grants start and stop working without an invalidation
+type cachedUser struct {
+	subject models.User       // Attributes referenced by row-level policies (id, email, region)
+	grants  []models.UserRole // Role assignments, including inactive time-bound ones
+	expires time.Time
+}
+
+// roleIds returns the roles whose assignment is active at the given time
+func (user cachedUser) roleIds(now time.Time) []uint {
+	roleIds := make([]uint, 0, len(user.grants))
+	for _, grant := range user.grants {
+		if grant.ActiveAt(now) {
+			roleIds = append(roleIds, grant.RoleId)
+		}
+	}
+	return roleIds
+}
+
+// holds reports whether the user has any assignment of a role, active or not
+func (user cachedUser) holds(roleId uint) bool {
+	for _, grant := range user.grants {
+		if grant.RoleId == roleId {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedRole is a cache entry holding the permission names and row-level policies of a role
+type cachedRole struct {
+	permissions map[string]bool
+	policies    []models.Policy
+	expires     time.Time
+}
+
+// cache is the process-wide permission cache used by IsAuthorized
+var cache = &permissionCache{
+	users: map[uint]cachedUser{},
+	roles: map[uint]cachedRole{},
+	ttl:   util.GetenvDuration("PERMISSION_CACHE_TTL", 5*time.Minute),
+}
+
+// UserPermissions returns the set of permission names granted to a user
+// Effective permissions are the union of the permissions of all the user's roles
+// Served from the cache when possible; otherwise loads the user's roles and their
+// permissions from the database and caches the result
+// Returns the database error if they could not be loaded
+func UserPermissions(userId uint) (map[string]bool, error) {
+	return cache.resolve(context.Background(), userId)
+}
+
+// resolve returns the union of the permission sets of the roles actively assigned to a user
+// Cache misses are loaded with ctx, so they are traced under the caller's span
+func (pc *permissionCache) resolve(ctx context.Context, userId uint) (map[string]bool, error) {
+	user, err := pc.user(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	roleIds := user.roleIds(time.Now())
+
+	// Single role - its cached set can be returned as is
+	if len(roleIds) == 1 {
+		role, err := pc.role(ctx, roleIds[0])
+		return role.permissions, err
+	}
+
+	permissions := map[string]bool{}
+	for _, roleId := range roleIds {
+		role, err := pc.role(ctx, roleId)
+		if err != nil {
+			return nil, err
+		}
+		for name := range role.permissions {
+			permissions[name] = true
+		}
+	}
+	return permissions, nil
+}
+
+// user resolves the role assignments and policy attributes of a user
+// A failed lookup is returned and not cached, so the next request retries it
+func (pc *permissionCache) user(ctx context.Context, userId uint) (cachedUser, error) {
+	now := time.Now()
+
+	pc.mu.RLock()
+	user, ok := pc.users[userId]
+	generation := pc.generation
+	pc.mu.RUnlock()
+
+	if ok && now.Before(user.expires) {
+		return user, nil
+	}
+
+	// Cache miss - load the attributes used by policies and the user's role assignments
+	var subject models.User
+	if err := database.DB.WithContext(ctx).Select("id", "email", "region").Where("id = ?", userId).Find(&subject).Error; err != nil {
+		return cachedUser{}, err
+	}
+
+	var grants []models.UserRole
+	if err := database.DB.WithContext(ctx).Select("user_id", "role_id", "starts_at", "expires_at").Where("user_id = ?", userId).Find(&grants).Error; err != nil {
+		return cachedUser{}, err
+	}
+
+	user = cachedUser{subject: subject, grants: grants, expires: now.Add(pc.ttl)}
+	pc.store(generation, func() { pc.users[userId] = user })
+	return user, nil
+}
+
+// role resolves the permission names granted by a role, including inherited ones, and its policies
+// A failed lookup is returned and not cached, so the next request retries it
+func (pc *permissionCache) role(ctx context.Context, roleId uint) (cachedRole, error) {
+	now := time.Now()
+
+	pc.mu.RLock()
+	role, ok := pc.roles[roleId]
+	generation := pc.generation
+	pc.mu.RUnlock()
+
+	if ok && now.Before(role.expires) {
+		return role, nil
+	}
+
+	// Cache miss - load the role with its direct permissions and policies
+	record := models.Role{
+		Id: roleId,
+	}
+	if err := database.DB.WithContext(ctx).Preload("Permissions").Preload("Policies").Find(&record).Error; err != nil {
+		return cachedRole{}, err
+	}
+
+	// A broken hierarchy (cycle or missing parent) grants only the direct permissions
+	err := record.LoadInheritedPermissions(database.DB.WithContext(ctx))
+	if err != nil && !errors.Is(err, models.ErrRoleCycle) && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return cachedRole{}, err
+	}
+
+	permissions := make(map[string]bool, len(record.Permissions)+len(record.InheritedPermissions))
+	for _, permission := range record.Permissions {
+		permissions[permission.Name] = true
+	}
+	for _, permission := range record.InheritedPermissions {
+		permissions[permission.Name] = true
+	}
+
+	role = cachedRole{permissions: permissions, policies: record.Policies, expires: now.Add(pc.ttl)}
+	pc.store(generation, func() { pc.roles[roleId] = role })
+	return role, nil
+}
+
+// store applies a cache write unless an invalidation happened since generation was read
+// Prevents a lookup that raced with an update from caching stale data
+func (pc *permissionCache) store(generation uint64, write func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.generation == generation {
+		write()
+	}
+}
+
+// InvalidateUser drops the cached role assignments and attributes of a user
+// Must be called after a user's roles or region change or the user is deleted
+func InvalidateUser(userId uint) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	delete(cache.users, userId)
+}
+
+// InvalidateRole drops the cached permission sets of a role, of every role inheriting
+// from it, and the assignments of every user holding it
+// Must be called after a role's permissions or parent change or the role is deleted
+func InvalidateRole(roleId uint) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Descendant roles embed this role's permissions, so every role set is dropped
+	cache.generation++
+	cache.roles = map[uint]cachedRole{}
+	for userId, user := range cache.users {
+		if user.holds(roleId) {
+			delete(cache.users, userId)
+		}
+	}
+}
+
+// InvalidatePermissions drops every cached permission set
+// Must be called after permissions themselves are created, renamed or deleted
+func InvalidatePermissions() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	cache.roles = map[uint]cachedRole{}
+}
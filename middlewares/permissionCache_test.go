@@ -0,0 +1,245 @@
+package middlewares
+
+import (
+	"errors"
+	"go-admin/database"
+	"go-admin/models"
+	"go-admin/util"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mockDatabase replaces database.DB with a GORM connection backed by sqlmock and resets the permission cache
+// Expectations are matched by table in any order, since preloads do not run in a fixed order
+// Returns the mock and a counter of the queries GORM issued
+func mockDatabase(tb testing.TB) (sqlmock.Sqlmock, *atomic.Int64) {
+	tb.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	// Count every query, including those sqlmock does not expect
+	queries := &atomic.Int64{}
+	err = db.Callback().Query().Before("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries.Add(1)
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	cache = &permissionCache{users: map[uint]cachedUser{}, roles: map[uint]cachedRole{}, ttl: time.Hour}
+
+	tb.Cleanup(func() {
+		database.DB = previous
+		conn.Close()
+	})
+	return mock, queries
+}
+
+// expectUser expects the queries loading a user and their permanent role assignments
+func expectUser(mock sqlmock.Sqlmock, userId uint, roleIds ...uint) {
+	grants := make([]models.UserRole, len(roleIds))
+	for i, roleId := range roleIds {
+		grants[i] = models.UserRole{UserId: userId, RoleId: roleId}
+	}
+	expectSubject(mock, models.User{Id: userId, Email: "jane@example.com", Region: "eu"}, grants...)
+}
+
+// expectSubject expects the queries loading a user's policy attributes and role assignments,
+// including their validity windows
+func expectSubject(mock sqlmock.Sqlmock, subject models.User, grants ...models.UserRole) {
+	mock.ExpectQuery("FROM `users`").WithArgs(subject.Id).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "email", "region"}).AddRow(subject.Id, subject.Email, subject.Region))
+
+	rows := sqlmock.NewRows([]string{"user_id", "role_id", "starts_at", "expires_at"})
+	for _, grant := range grants {
+		rows.AddRow(subject.Id, grant.RoleId, grant.StartsAt, grant.ExpiresAt)
+	}
+	mock.ExpectQuery("FROM `user_roles`").WithArgs(subject.Id).WillReturnRows(rows)
+}
+
+// expectRole expects the queries loading a role without parent or policies and its permissions
+func expectRole(mock sqlmock.Sqlmock, roleId uint, permissions ...string) {
+	expectRestrictedRole(mock, roleId, nil, permissions...)
+}
+
+// expectRestrictedRole expects the queries loading a role without parent, its permissions and policies
+func expectRestrictedRole(mock sqlmock.Sqlmock, roleId uint, policies []models.Policy, permissions ...string) {
+	mock.ExpectQuery("FROM `roles`").WithArgs(roleId).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(roleId, "role "+strconv.Itoa(int(roleId)), nil))
+
+	grants := sqlmock.NewRows([]string{"role_id", "permission_id"})
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	for i, name := range permissions {
+		grants.AddRow(roleId, i+1)
+		rows.AddRow(i+1, name)
+	}
+	mock.ExpectQuery("FROM `role_permissions`").WillReturnRows(grants)
+	mock.ExpectQuery("FROM `permissions`").WillReturnRows(rows)
+
+	restrictions := sqlmock.NewRows([]string{"id", "role_id", "resource", "field", "value"})
+	for i, policy := range policies {
+		restrictions.AddRow(i+1, roleId, policy.Resource, policy.Field, policy.Value)
+	}
+	mock.ExpectQuery("FROM `policies`").WillReturnRows(restrictions)
+}
+
+// authenticatedCtx returns a request context carrying a valid JWT for the user
+func authenticatedCtx(tb testing.TB, app *fiber.App, userId uint) fiber.Ctx {
+	tb.Helper()
+
+	token, err := util.GenerateJWT(strconv.Itoa(int(userId)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	request := &fasthttp.RequestCtx{}
+	request.Request.Header.SetCookie("jwt", token)
+	c := app.AcquireCtx(request)
+	tb.Cleanup(func() { app.ReleaseCtx(c) })
+	return c
+}
+
+// BenchmarkIsAuthorizedWarm measures permission checks served from a warm cache
+// Fails if any check reaches the database
+func BenchmarkIsAuthorizedWarm(b *testing.B) {
+	mock, queries := mockDatabase(b)
+	expectUser(mock, 1, 2)
+	expectRole(mock, 2, "products:read")
+
+	app := fiber.New()
+	c := authenticatedCtx(b, app, 1)
+
+	// Warm the cache
+	if err := IsAuthorized(c, "products", "read"); err != nil {
+		b.Fatalf("warm-up check failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		b.Fatal(err)
+	}
+	queries.Store(0)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := IsAuthorized(c, "products", "read"); err != nil {
+			b.Fatalf("check failed: %v", err)
+		}
+		if permissions, _ := UserPermissions(1); !permissions["products:read"] {
+			b.Fatal("permission missing from cached set")
+		}
+	}
+
+	if n := queries.Load(); n != 0 {
+		b.Fatalf("warm path ran %d queries, want 0", n)
+	}
+}
+
+// TestInvalidateUserReloadsAssignments checks that InvalidateUser reloads the user's
+// role assignments on the next lookup while cached roles are reused
+func TestInvalidateUserReloadsAssignments(t *testing.T) {
+	mock, queries := mockDatabase(t)
+	expectUser(mock, 1, 2)
+	expectRole(mock, 2, "products:read")
+
+	if permissions, err := UserPermissions(1); err != nil || !permissions["products:read"] {
+		t.Fatalf("products:read not granted: %v", err)
+	}
+
+	// A second lookup is served from the cache
+	queries.Store(0)
+	UserPermissions(1)
+	if n := queries.Load(); n != 0 {
+		t.Fatalf("cached lookup ran %d queries, want 0", n)
+	}
+
+	// The user is moved to another role
+	InvalidateUser(1)
+	expectUser(mock, 1, 3)
+	expectRole(mock, 3, "orders:read")
+
+	permissions, err := UserPermissions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permissions["products:read"] || !permissions["orders:read"] {
+		t.Fatalf("permissions after InvalidateUser = %v, want only orders:read", permissions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInvalidateRoleReloadsPermissions checks that InvalidateRole reloads the role's
+// permission set and the assignments of its holders on the next lookup
+func TestInvalidateRoleReloadsPermissions(t *testing.T) {
+	mock, queries := mockDatabase(t)
+	expectUser(mock, 1, 2)
+	expectRole(mock, 2, "products:read")
+
+	if permissions, err := UserPermissions(1); err != nil || !permissions["products:read"] {
+		t.Fatalf("products:read not granted: %v", err)
+	}
+
+	// The role is granted another permission
+	InvalidateRole(2)
+	expectUser(mock, 1, 2)
+	expectRole(mock, 2, "products:read", "products:update")
+
+	queries.Store(0)
+	permissions, err := UserPermissions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permissions["products:read"] || !permissions["products:update"] {
+		t.Fatalf("permissions after InvalidateRole = %v, want products:read and products:update", permissions)
+	}
+	if queries.Load() == 0 {
+		t.Fatal("InvalidateRole did not force a reload")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFailedLookupIsNotCached checks that a database error is returned instead of
+// an empty permission set, and that the next lookup queries the database again
+func TestFailedLookupIsNotCached(t *testing.T) {
+	mock, _ := mockDatabase(t)
+	mock.ExpectQuery("FROM `users`").WillReturnError(errors.New("connection refused"))
+
+	if _, err := UserPermissions(1); err == nil {
+		t.Fatal("UserPermissions succeeded on a failed lookup")
+	}
+
+	expectUser(mock, 1, 2)
+	expectRole(mock, 2, "products:read")
+
+	permissions, err := UserPermissions(1)
+	if err != nil || !permissions["products:read"] {
+		t.Fatalf("permissions after a failed lookup = %v, %v, want products:read", permissions, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,65 +1,362 @@
 package middlewares
 
 import (
-	"errors"
-	"go-admin/database"
+	"context"
+	"fmt"
 	"go-admin/models"
+	"go-admin/tracing"
 	"go-admin/util"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// IsAuthorized checks if the authenticated user has permission to access a resource
+// PermissionError reports that an authenticated user lacks the permission required by a route
+// Returned by IsAuthorized so callers can distinguish "not allowed" (403) from "not authenticated" (401)
+type PermissionError struct {
+	Permission string // Name of the missing permission (e.g., "products:create")
+}
+
+// Error implements the error interface for PermissionError
+func (err *PermissionError) Error() string {
+	return "missing permission " + err.Permission
+}
+
+// RequirePermission creates a route-level middleware that enforces IsAuthorized for a resource
+// The action is derived from the HTTP method (see ActionForMethod)
+// Declared in routes.Setup so every protected route states the permission it requires
+// Parameters:
+//   - resource: resource name (e.g., "users", "products") to check permissions for
+//
+// Usage: app.Get("/api/products", middlewares.RequirePermission("products"), controllers.AllProducts)
+func RequirePermission(resource string) fiber.Handler {
+	return RequireAction(resource, "")
+}
+
+// RequireAction creates a route-level middleware that enforces IsAuthorized for an explicit action
+// Used for actions that do not map onto a CRUD method, such as "orders:export"
+// An empty action falls back to deriving it from the HTTP method
+//
+// Fails with 401 Unauthorized if the JWT token is invalid, with 403 Forbidden naming
+// the missing permission in details if the user is not allowed, or with 500 if the
+// user's permissions could not be loaded
+// Usage: app.Post("/api/export", middlewares.RequireAction("orders", models.ActionExport), controllers.Export)
+func RequireAction(resource string, action string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		required := action
+		if required == "" {
+			required = ActionForMethod(c.Method())
+		}
+
+		ctx, span := tracing.Start(c, "auth.authorize", trace.WithAttributes(
+			attribute.String("auth.resource", resource),
+			attribute.String("auth.action", required),
+		))
+		err := isAuthorized(ctx, c, resource, required)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		// A PermissionError is rendered as 403 by ErrorHandler, a database error as 500
+		if err != nil {
+			return err
+		}
+
+		// User holds the required permission - proceed to next handler
+		return c.Next()
+	}
+}
+
+// ActionForMethod maps an HTTP method onto the CRUD action it performs
+//   - GET/HEAD: read
+//   - POST: create
+//   - PUT/PATCH: update
+//   - DELETE: delete
+func ActionForMethod(method string) string {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead:
+		return models.ActionRead
+	case fiber.MethodPost:
+		return models.ActionCreate
+	case fiber.MethodDelete:
+		return models.ActionDelete
+	default:
+		return models.ActionUpdate
+	}
+}
+
+// IsAuthorized checks if the authenticated user may perform an action on a resource
 // Implements role-based access control (RBAC) by validating user permissions
-// Extracts user from JWT token, loads their role and permissions, then checks access
+// Extracts user from JWT token and checks the permission set of their role,
+// which is resolved through the in-memory permission cache (see UserPermissions)
 // Parameters:
-//   - page: resource name (e.g., "users", "products") to check permissions for
+//   - resource: resource name (e.g., "users", "products") to check permissions for
+//   - action: action to perform (e.g., models.ActionRead, models.ActionExport)
 //
 // Permission naming convention:
-//   - GET requests require "view_<page>" or "edit_<page>" permission
-//   - POST/PUT/DELETE requests require "edit_<page>" permission
+//   - "<resource>:<action>" grants exactly that action (e.g., "products:create")
+//   - legacy "view_<resource>" still grants read, and "edit_<resource>" still grants every action
 //
-// Returns error with 401 Unauthorized if user lacks required permission
-func IsAuthorized(c fiber.Ctx, page string) error {
+// Returns a *PermissionError naming the missing permission if the user lacks access,
+// util.Unauthorized() if the token is invalid, or the database error if the user's
+// permissions could not be loaded
+func IsAuthorized(c fiber.Ctx, resource string, action string) error {
+	return isAuthorized(c, c, resource, action)
+}
+
+// isAuthorized implements IsAuthorized, loading uncached permissions with ctx
+// so the lookups are traced under the span of the permission check
+func isAuthorized(ctx context.Context, c fiber.Ctx, resource string, action string) error {
 	// Extract and validate JWT token
 	cookie := c.Cookies("jwt")
 	Id, err := util.ParseJWT(cookie)
 	if err != nil {
-		return err
+		return util.Unauthorized()
 	}
 
-	// Load user with their role
+	// Resolve the permission set of the user's role
 	userId, _ := strconv.Atoi(Id)
-	user := models.User{
-		Id: uint(userId),
+	permissions, err := cache.resolve(ctx, uint(userId))
+	if err != nil {
+		return err
+	}
+
+	if granted(permissions, resource, action) {
+		return nil
+	}
+	return &PermissionError{Permission: models.PermissionName(resource, action)}
+}
+
+// AuthorizeRoleAssignment checks if the authenticated user may assign the given roles to target
+// Assigning a role hands out its permissions, so holding users:update is not enough:
+// the user must either hold roles:update, or already hold every permission the roles
+// grant (including inherited ones), so no one can give out more access than they have
+// A permission only counts as held through a role whose row-level policies on the permission's
+// resource are no broader than the assigned role's, each resolved for its own holder
+// (see holdsPermission), so a user limited to their region cannot grant an unrestricted role
+// Without roles:update, two further limits apply:
+//   - users may not change their own role assignments
+//   - an assignment may not outlast the caller's own access: if the caller holds a permission
+//     of the roles only through time-bound grants, expiresAt must be set and no later than
+//     the end of the latest of those grants
+//
+// target is the user receiving the roles (Id 0 for accounts being created), carrying the
+// attributes referenced by policies (id, email, region), and expiresAt the end of the assignment (nil for a permanent one)
+// Returns a *PermissionError naming roles:update if the user may not, a 403 util.Error naming
+// the latest allowed expires_at in details if the assignment would outlast the caller's access,
+// util.Unauthorized() if the token is invalid, or the database error if a permission set could not be loaded
+func AuthorizeRoleAssignment(c fiber.Ctx, target models.User, roleIds []uint, expiresAt *time.Time) error {
+	deadline, err := roleAuthority(c, target, roleIds)
+	if err != nil {
+		return err
+	}
+
+	// The assignment must end when the caller's authority over the roles does
+	if deadline != nil && (expiresAt == nil || expiresAt.After(*deadline)) {
+		return util.Forbidden("role assignment would outlast your own access").WithDetails(fiber.Map{
+			"permission": models.PermissionName(models.ResourceRoles, models.ActionUpdate),
+			"expires_at": deadline,
+		})
+	}
+	return nil
+}
+
+// AuthorizeRoleRevocation checks if the authenticated user may revoke the given roles from target
+// Requires the same authority over the roles as AuthorizeRoleAssignment, without the expiry limit
+func AuthorizeRoleRevocation(c fiber.Ctx, target models.User, roleIds []uint) error {
+	_, err := roleAuthority(c, target, roleIds)
+	return err
+}
+
+// roleAuthority checks that the authenticated user has authority over the given roles of target
+// Returns the time the caller's authority ends, or nil if it does not end
+// (including for holders of roles:update)
+func roleAuthority(c fiber.Ctx, target models.User, roleIds []uint) (*time.Time, error) {
+	if len(roleIds) == 0 {
+		return nil, nil
+	}
+
+	err := IsAuthorized(c, models.ResourceRoles, models.ActionUpdate)
+	if _, ok := err.(*PermissionError); !ok {
+		return nil, err
+	}
+
+	// Without roles:update, users may not change their own assignments
+	id, _ := util.ParseJWT(c.Cookies("jwt"))
+	userId, _ := strconv.Atoi(id)
+	if target.Id == uint(userId) {
+		return nil, err
+	}
+
+	caller, lookupErr := cache.user(c, uint(userId))
+	if lookupErr != nil {
+		return nil, lookupErr
 	}
-	database.DB.Preload("Role").Find(&user)
 
-	// Load role with associated permissions
-	role := models.Role{
-		Id: user.RoleId,
+	// Every permission of the roles must be held through an active grant of the caller
+	// whose policies are no broader than those the role would apply to target
+	var deadline *time.Time
+	for _, roleId := range roleIds {
+		role, lookupErr := cache.role(c, roleId)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		for name := range role.permissions {
+			resource := permissionResource(name)
+			held, until, lookupErr := holdsPermission(c, caller, name, policyRestrictions(role.policies, resource, target))
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			if !held {
+				return nil, err
+			}
+			if until != nil && (deadline == nil || until.Before(*deadline)) {
+				deadline = until
+			}
+		}
 	}
-	database.DB.Preload("Permissions").Find(&role)
+	return deadline, nil
+}
+
+// holdsPermission reports whether a user holds a permission through an active role assignment
+// whose role is restricted on the permission's resource by at least the given restrictions
+// (see policyRestrictions); a role without policies on the resource only covers unrestricted access
+// Also returns the time the latest of those assignments expires, or nil if one of them is permanent
+func holdsPermission(ctx context.Context, user cachedUser, name string, restrictions map[string]bool) (bool, *time.Time, error) {
+	resource := permissionResource(name)
+	now := time.Now()
+	held := false
+	var until *time.Time
 
-	// Check permissions based on HTTP method
-	if c.Method() == "GET" {
-		// GET requests require view or edit permission
-		for _, permission := range role.Permissions {
-			if permission.Name == "view_"+page || permission.Name == "edit_"+page {
-				return nil
+	for _, grant := range user.grants {
+		if !grant.ActiveAt(now) {
+			continue
+		}
+
+		role, err := cache.role(ctx, grant.RoleId)
+		if err != nil {
+			return false, nil, err
+		}
+		if !role.permissions[name] {
+			continue
+		}
+
+		// The role's policies must not let the user see rows the restrictions hide
+		broader := false
+		for restriction := range policyRestrictions(role.policies, resource, user.subject) {
+			if !restrictions[restriction] {
+				broader = true
+				break
 			}
 		}
-	} else {
-		// POST/PUT/DELETE requests require edit permission
-		for _, permission := range role.Permissions {
-			if permission.Name == "edit_"+page {
-				return nil
+		if broader {
+			continue
+		}
+
+		// A permanent grant outweighs any time-bound one
+		if grant.ExpiresAt == nil {
+			return true, nil, nil
+		}
+		if !held || grant.ExpiresAt.After(*until) {
+			until = grant.ExpiresAt
+		}
+		held = true
+	}
+	return held, until, nil
+}
+
+// policyRestrictions returns the policies on a resource, resolved for subject, as "<field>=<value>" conditions
+// A set of conditions is no broader than another if it is contained in it
+func policyRestrictions(policies []models.Policy, resource string, subject models.User) map[string]bool {
+	restrictions := map[string]bool{}
+	for _, policy := range policies {
+		if policy.Resource == resource {
+			restrictions[fmt.Sprintf("%s=%v", policy.Field, policyValue(policy, subject))] = true
+		}
+	}
+	return restrictions
+}
+
+// permissionResource returns the resource a permission name applies to
+// Example: "orders:read", "orders.email:read" and "edit_orders" all apply to "orders"
+func permissionResource(name string) string {
+	for _, prefix := range []string{"view_", "edit_"} {
+		if resource, ok := strings.CutPrefix(name, prefix); ok {
+			return resource
+		}
+	}
+
+	resource, _, _ := strings.Cut(name, ":")
+	resource, _, _ = strings.Cut(resource, ".")
+	return resource
+}
+
+// granted reports whether a permission set allows an action on a resource
+// Checks the action-level permission first, then falls back to legacy
+// view_/edit_ permissions during the transition
+func granted(permissions map[string]bool, resource string, action string) bool {
+	if permissions[models.PermissionName(resource, action)] {
+		return true
+	}
+
+	for _, legacy := range models.LegacyPermissionNames(resource, action) {
+		if permissions[legacy] {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectivePermissions resolves the permission names and per-resource actions granted to a user
+// Every (resource, action) pair implied by the user's permissions is checked with the same
+// granted function IsAuthorized uses, so the result never disagrees with the server
+// Legacy "view_"/"edit_" permissions are expanded into the actions they grant
+// Returns the database error if the user's permissions could not be loaded
+func EffectivePermissions(userId uint) (models.EffectivePermissions, error) {
+	permissions, err := UserPermissions(userId)
+	if err != nil {
+		return models.EffectivePermissions{}, err
+	}
+
+	names := make([]string, 0, len(permissions))
+	actions := map[string][]string{}
+
+	for name := range permissions {
+		names = append(names, name)
+
+		// Collect the action permissions this name stands for
+		candidates := models.ActionPermissionNames(name)
+		if candidates == nil {
+			candidates = []string{name}
+		}
+
+		for _, candidate := range candidates {
+			resource, action, ok := strings.Cut(candidate, ":")
+			if !ok || slices.Contains(actions[resource], action) {
+				continue
+			}
+			if granted(permissions, resource, action) {
+				actions[resource] = append(actions[resource], action)
 			}
 		}
 	}
 
-	// User lacks required permission
-	c.Status(fiber.StatusUnauthorized)
-	return errors.New("unauthorized")
+	// Sort for stable responses
+	slices.Sort(names)
+	for resource := range actions {
+		slices.Sort(actions[resource])
+	}
+
+	return models.EffectivePermissions{
+		Permissions: names,
+		Actions:     actions,
+	}, nil
 }
@@ -0,0 +1,214 @@
+package middlewares
+
+import (
+	"errors"
+	"go-admin/models"
+	"go-admin/util"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v3"
+)
+
+// TestAuthorizeRoleAssignment checks which role assignments a caller without roles:update may make
+// The caller is user 1 in region "eu"; role 10 is assigned to user 5 unless stated otherwise
+func TestAuthorizeRoleAssignment(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	earlier := soon.Add(-time.Minute)
+	later := soon.Add(time.Minute)
+	sameRegion := []models.Policy{{Resource: models.ResourceUsers, Field: "region", Value: "$user.region"}}
+
+	// Outcomes of an authorization check
+	const (
+		allowed   = "allowed"
+		forbidden = "missing roles:update"
+		outlasts  = "outlasts the caller's access"
+	)
+
+	tests := []struct {
+		name      string
+		target    models.User
+		expiresAt *time.Time
+		expect    func(mock sqlmock.Sqlmock)
+		want      string
+	}{
+		{
+			name:   "roles:update",
+			target: models.User{Id: 5},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectUser(mock, 1, 2)
+				expectRole(mock, 2, "roles:update")
+			},
+			want: allowed,
+		},
+		{
+			name:   "roles:update on own assignments",
+			target: models.User{Id: 1},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectUser(mock, 1, 2)
+				expectRole(mock, 2, "roles:update")
+			},
+			want: allowed,
+		},
+		{
+			name:   "every permission of the role",
+			target: models.User{Id: 5},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectUser(mock, 1, 2)
+				expectRole(mock, 2, "products:read", "products:update")
+				expectRole(mock, 10, "products:read")
+			},
+			want: allowed,
+		},
+		{
+			name:   "permission the caller lacks",
+			target: models.User{Id: 5},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectUser(mock, 1, 2)
+				expectRole(mock, 2, "products:read")
+				expectRole(mock, 10, "products:read", "products:delete")
+			},
+			want: forbidden,
+		},
+		{
+			name:   "own assignments",
+			target: models.User{Id: 1},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectUser(mock, 1, 2)
+				expectRole(mock, 2, "products:read")
+			},
+			want: forbidden,
+		},
+		{
+			name:   "permanent grant from a time-bound one",
+			target: models.User{Id: 5},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2, ExpiresAt: &soon})
+				expectRole(mock, 2, "products:read")
+				expectRole(mock, 10, "products:read")
+			},
+			want: outlasts,
+		},
+		{
+			name:      "grant outlasting a time-bound one",
+			target:    models.User{Id: 5},
+			expiresAt: &later,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2, ExpiresAt: &soon})
+				expectRole(mock, 2, "products:read")
+				expectRole(mock, 10, "products:read")
+			},
+			want: outlasts,
+		},
+		{
+			name:      "grant ending before a time-bound one",
+			target:    models.User{Id: 5},
+			expiresAt: &earlier,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2, ExpiresAt: &soon})
+				expectRole(mock, 2, "products:read")
+				expectRole(mock, 10, "products:read")
+			},
+			want: allowed,
+		},
+		{
+			name:   "permanent grant next to a time-bound one",
+			target: models.User{Id: 5},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"},
+					models.UserRole{RoleId: 2, ExpiresAt: &soon}, models.UserRole{RoleId: 3})
+				expectRole(mock, 2, "products:read")
+				expectRole(mock, 3, "products:read")
+				expectRole(mock, 10, "products:read")
+			},
+			want: allowed,
+		},
+		{
+			name:   "unrestricted role from a restricted one",
+			target: models.User{Id: 5, Region: "eu"},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2})
+				expectRestrictedRole(mock, 2, sameRegion, "users:read")
+				expectRole(mock, 10, "users:read")
+			},
+			want: forbidden,
+		},
+		{
+			name:   "restricted role within the caller's region",
+			target: models.User{Id: 5, Region: "eu"},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2})
+				expectRestrictedRole(mock, 2, sameRegion, "users:read")
+				expectRestrictedRole(mock, 10, sameRegion, "users:read")
+			},
+			want: allowed,
+		},
+		{
+			name:   "restricted role outside the caller's region",
+			target: models.User{Id: 5, Region: "us"},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2})
+				expectRestrictedRole(mock, 2, sameRegion, "users:read")
+				expectRestrictedRole(mock, 10, sameRegion, "users:read")
+			},
+			want: forbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock, _ := mockDatabase(t)
+			test.expect(mock)
+			c := authenticatedCtx(t, fiber.New(), 1)
+
+			err := AuthorizeRoleAssignment(c, test.target, []uint{10}, test.expiresAt)
+			if got := authorizationOutcome(err); got != test.want {
+				t.Fatalf("AuthorizeRoleAssignment() = %v (%s), want %s", err, got, test.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestAuthorizeRoleRevocation checks that revoking requires authority over the role but no expiry
+func TestAuthorizeRoleRevocation(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+
+	mock, _ := mockDatabase(t)
+	expectSubject(mock, models.User{Id: 1, Region: "eu"}, models.UserRole{RoleId: 2, ExpiresAt: &soon})
+	expectRole(mock, 2, "products:read")
+	expectRole(mock, 10, "products:read")
+	c := authenticatedCtx(t, fiber.New(), 1)
+
+	if err := AuthorizeRoleRevocation(c, models.User{Id: 5}, []uint{10}); err != nil {
+		t.Fatalf("revoking a role within a time-bound grant: %v", err)
+	}
+
+	// Revoking one's own roles changes one's own assignments too
+	var permissionErr *PermissionError
+	if err := AuthorizeRoleRevocation(c, models.User{Id: 1}, []uint{10}); !errors.As(err, &permissionErr) {
+		t.Fatalf("revoking an own role = %v, want a PermissionError", err)
+	}
+}
+
+// authorizationOutcome classifies the result of an authorization check
+func authorizationOutcome(err error) string {
+	var permissionErr *PermissionError
+	if errors.As(err, &permissionErr) && permissionErr.Permission == "roles:update" {
+		return "missing roles:update"
+	}
+
+	var appErr *util.Error
+	if errors.As(err, &appErr) && appErr.Code == http.StatusForbidden {
+		return "outlasts the caller's access"
+	}
+
+	if err == nil {
+		return "allowed"
+	}
+	return "unexpected error"
+}
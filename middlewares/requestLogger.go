@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"go-admin/tracing"
+	"go-admin/util"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIdPattern accepts client supplied request IDs that are safe to log and echo back
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestLogger assigns every request a correlation ID and logs it once it has been handled
+// An incoming X-Request-ID header is propagated when it is well-formed; otherwise a new ID is generated
+// The ID is returned in the X-Request-ID response header, and a logger tagged with it is made
+// available to handlers and database queries through util.Log(c)
+// Logged fields: request_id, method, path, query (sensitive parameters redacted), status, latency, ip, user_id
+// Usage: app.Use(middlewares.RequestLogger) right after middlewares.Tracing
+func RequestLogger(c fiber.Ctx) error {
+	start := time.Now()
+
+	requestId := c.Get(fiber.HeaderXRequestID)
+	if !requestIdPattern.MatchString(requestId) {
+		requestId = newRequestId()
+	}
+	c.Set(fiber.HeaderXRequestID, requestId)
+
+	logger := util.Logger.With("request_id", requestId)
+
+	// Tag log lines with the trace ID so they can be matched to the request's spans
+	if spanContext := trace.SpanContextFromContext(tracing.Context(c)); spanContext.IsValid() {
+		logger = logger.With("trace_id", spanContext.TraceID().String())
+	}
+	c.Locals(util.RequestIdKey, requestId)
+	c.Locals(util.LoggerKey, logger)
+
+	// Let the error handler write the response now, so the logged status is the one sent
+	err := c.Next()
+	if err != nil {
+		if handlerErr := c.App().Config().ErrorHandler(c, err); handlerErr != nil {
+			c.Status(fiber.StatusInternalServerError)
+		}
+	}
+
+	status := c.Response().StatusCode()
+	attributes := []any{
+		"method", c.Method(),
+		"path", c.Path(),
+		"query", redactQuery(string(c.Request().URI().QueryString())),
+		"status", status,
+		"latency", time.Since(start),
+		"ip", c.IP(),
+		"user_id", requestUserId(c),
+	}
+
+	switch {
+	case status >= 500:
+		logger.Error("request failed", append(attributes, "error", err)...)
+	case status >= 400:
+		logger.Warn("request rejected", attributes...)
+	default:
+		logger.Info("request handled", attributes...)
+	}
+	return nil
+}
+
+// newRequestId generates a random 128-bit request ID
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// redactQuery hides the values of sensitive query parameters (e.g., ?token=...)
+func redactQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "[UNPARSEABLE]"
+	}
+	for key := range values {
+		if util.IsSensitive(key) {
+			values.Set(key, "[REDACTED]")
+		}
+	}
+	return values.Encode()
+}
+
+// requestUserId returns the ID of the authenticated user, or 0 for anonymous requests
+func requestUserId(c fiber.Ctx) uint {
+	id, err := util.ParseJWT(c.Cookies("jwt"))
+	if err != nil {
+		return 0
+	}
+	userId, _ := strconv.Atoi(id)
+	return uint(userId)
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"go-admin/models"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// RouteAccess describes the access requirement declared by a route
+// Collected at startup so the permissions referenced by routes can be created
+// automatically and listed through the API
+type RouteAccess struct {
+	Method        string `json:"method"`               // HTTP method (e.g., "GET")
+	Path          string `json:"path"`                 // Route path as registered (e.g., "/api/users/:id")
+	Authenticated bool   `json:"authenticated"`        // Whether a valid JWT is required
+	Resource      string `json:"resource,omitempty"`   // Guarded resource (empty when no permission is required)
+	Action        string `json:"action,omitempty"`     // Required action on the resource
+	Permission    string `json:"permission,omitempty"` // Required permission name (e.g., "users:read")
+}
+
+// routeRegistry collects the access requirements of every declared route
+type routeRegistry struct {
+	mu     sync.RWMutex
+	routes map[string]RouteAccess // "METHOD path" -> access requirement
+}
+
+// registry is the process-wide route registry filled by the routes package
+var registry = &routeRegistry{
+	routes: map[string]RouteAccess{},
+}
+
+// DeclareRoute records the access requirement of a route
+// The permission name is derived from resource and action when a resource is given
+func DeclareRoute(route RouteAccess) {
+	if route.Resource != "" {
+		route.Authenticated = true
+		route.Permission = models.PermissionName(route.Resource, route.Action)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.routes[route.Method+" "+route.Path] = route
+}
+
+// DeclaredRoute returns the access requirement recorded for a route
+// Reports false when the route was never declared
+func DeclaredRoute(method string, path string) (RouteAccess, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	route, ok := registry.routes[method+" "+path]
+	return route, ok
+}
+
+// DeclaredRoutes returns every declared route, ordered by path and method
+func DeclaredRoutes() []RouteAccess {
+	registry.mu.RLock()
+	routes := make([]RouteAccess, 0, len(registry.routes))
+	for _, route := range registry.routes {
+		routes = append(routes, route)
+	}
+	registry.mu.RUnlock()
+
+	slices.SortFunc(routes, func(a, b RouteAccess) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
+
+// DeclaredPermissions returns the sorted, distinct permission names required by declared routes
+func DeclaredPermissions() []string {
+	var names []string
+	for _, route := range DeclaredRoutes() {
+		if route.Permission != "" && !slices.Contains(names, route.Permission) {
+			names = append(names, route.Permission)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SyncPermissions creates the Permission rows required by declared routes that are missing
+// from the database, so no route depends on a permission someone has to insert by hand
+// Existing permissions are never modified or removed
+// Returns the names of the permissions that were created
+func SyncPermissions(db *gorm.DB) ([]string, error) {
+	required := DeclaredPermissions()
+
+	var existing []string
+	if err := db.Model(&models.Permission{}).Where("name IN ?", required).Pluck("name", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	var missing []models.Permission
+	var created []string
+	for _, name := range required {
+		if !slices.Contains(existing, name) {
+			missing = append(missing, models.Permission{Name: name})
+			created = append(created, name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	if err := db.Create(&missing).Error; err != nil {
+		return nil, err
+	}
+
+	// New permissions change what roles can be granted, drop any cached sets
+	InvalidatePermissions()
+	return created, nil
+}
@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"go-admin/tracing"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace of an incoming
+// W3C traceparent header when present
+// The span context is stored under tracing.ContextKey, so spans started from the fiber.Ctx
+// (authentication, permission checks, database queries through DB.WithContext(c)) become its children
+// The span is named after the route pattern (e.g., "GET /api/users/:id") once the route is known
+// Usage: app.Use(middlewares.Tracing) before any other middleware
+func Tracing(c fiber.Ctx) error {
+	parent := otel.GetTextMapPropagator().Extract(c, headerCarrier{c})
+	ctx, span := tracing.Tracer.Start(parent, c.Method(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("url.path", c.Path()),
+			attribute.String("client.address", c.IP()),
+		))
+	defer span.End()
+
+	c.Locals(tracing.ContextKey, ctx)
+
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if err != nil {
+		status = ResolveError(err).Code
+		span.RecordError(err)
+	}
+
+	route := c.Route().Path
+	span.SetName(c.Method() + " " + route)
+	span.SetAttributes(
+		attribute.String("http.route", route),
+		attribute.Int("http.response.status_code", status),
+	)
+	if status >= 500 {
+		span.SetStatus(codes.Error, fiber.ErrInternalServerError.Message)
+	}
+
+	return err
+}
+
+// headerCarrier adapts the request headers to the OpenTelemetry propagation.TextMapCarrier
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+// Get returns the value of a request header
+func (carrier headerCarrier) Get(key string) string {
+	return carrier.c.Get(key)
+}
+
+// Set sets a request header
+func (carrier headerCarrier) Set(key string, value string) {
+	carrier.c.Request().Header.Set(key, value)
+}
+
+// Keys returns the names of the request headers
+func (carrier headerCarrier) Keys() []string {
+	keys := []string{}
+	for key := range carrier.c.Request().Header.All() {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audited actions
+const (
+	AuditCreate = "create" // Entity was created
+	AuditUpdate = "update" // Entity was modified
+	AuditDelete = "delete" // Entity was removed
+)
+
+// Audited entity types, named after the table of the changed entity
+// Entity types of resources equal the resource name, so ProtectedFields applies to their snapshots
+const (
+	EntityUsers       = ResourceUsers    // User accounts
+	EntityUserRoles   = "user_roles"     // Role assignments of users
+	EntityInvitations = "invitations"    // User invitations
+	EntityRoles       = ResourceRoles    // Roles, including their permissions and policies
+	EntityPermissions = "permissions"    // Permissions
+	EntityProducts    = ResourceProducts // Products, including their stock level
+	EntityCategories  = "categories"     // Product categories
+	EntityTags        = "tags"           // Product tags
+	EntityVariants    = "variants"       // Product variants, including their stock level
+	EntityOptionTypes = "option_types"   // Variant option types
+)
+
+// AuditLog records a single mutating admin action
+// Written in the same transaction as the change, so an entry exists if and only if the change was committed
+type AuditLog struct {
+	Id             uint      `json:"id"`                                                // Primary key
+	ActorId        *uint     `json:"actor_id" gorm:"index"`                             // User who performed the action
+	ImpersonatorId *uint     `json:"impersonator_id"`                                   // User acting on behalf of the actor, filled from the session once impersonation exists (nil until then)
+	Ip             string    `json:"ip"`                                                // Client IP address
+	Method         string    `json:"method"`                                            // HTTP method of the request
+	Route          string    `json:"route"`                                             // Route pattern of the request (e.g., "/api/users/:id")
+	Action         string    `json:"action" gorm:"size:16"`                             // One of create, update, delete
+	EntityType     string    `json:"entity_type" gorm:"size:32;index:idx_audit_entity"` // Table of the changed entity (e.g., "users", "roles", "products")
+	EntityId       uint      `json:"entity_id" gorm:"index:idx_audit_entity"`           // Primary key of the changed entity
+	Changes        string    `json:"changes" gorm:"type:text"`                          // JSON object mapping each changed field to its before/after values
+	CreatedAt      time.Time `json:"created_at" gorm:"index"`                           // Time of the action
+}
+
+// AuditChange holds the before and after value of a changed field
+type AuditChange struct {
+	Before interface{} `json:"before"` // Value before the change (nil for created entities)
+	After  interface{} `json:"after"`  // Value after the change (nil for deleted entities)
+}
+
+// TableName stores audit entries in the audit_log table
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// Count implements the Entity interface for AuditLog
+// Returns the total number of audit entries matching the conditions on db
+// Used by the Paginate function for pagination metadata
+func (log *AuditLog) Count(db *gorm.DB) (int64, error) {
+	var total int64
+	err := db.Model(&AuditLog{}).Count(&total).Error
+	return total, err
+}
+
+// Take implements the Entity interface for AuditLog
+// Retrieves a paginated subset of audit entries, most recent first
+func (log *AuditLog) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
+	var logs []AuditLog
+	err := db.Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// MaskFields implements the FieldMasker interface for AuditLog
+// Masks the before and after values of every changed field the caller may not read on the
+// entry's entity type (e.g., users.email for user entries); protected fields are email addresses
+// Snapshots keep the full values, so callers holding the field permission still see them
+func (log *AuditLog) MaskFields(readable func(field string) bool) {
+	changes := map[string]AuditChange{}
+	if err := json.Unmarshal([]byte(log.Changes), &changes); err != nil {
+		// Never hand out changes that could not be checked
+		log.Changes = "{}"
+		return
+	}
+
+	masked := false
+	for field, change := range changes {
+		if readable(field) {
+			continue
+		}
+		changes[field] = AuditChange{Before: maskAuditValue(change.Before), After: maskAuditValue(change.After)}
+		masked = true
+	}
+	if !masked {
+		return
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		log.Changes = "{}"
+		return
+	}
+	log.Changes = string(encoded)
+}
+
+// maskAuditValue masks a recorded value of a protected field
+func maskAuditValue(value interface{}) interface{} {
+	if email, ok := value.(string); ok {
+		return MaskEmail(email)
+	}
+	return nil
+}
+
+// AuditDiff computes the fields that differ between two snapshots of an entity
+// Snapshots are compared through their JSON representation, so fields hidden from JSON
+// (e.g., password hashes) never appear in the audit log
+// Pass nil as before for created entities and as after for deleted ones
+// Returns a JSON object mapping each changed field to an AuditChange
+func AuditDiff(before interface{}, after interface{}) (string, error) {
+	old, err := auditFields(before)
+	if err != nil {
+		return "", err
+	}
+	current, err := auditFields(after)
+	if err != nil {
+		return "", err
+	}
+
+	changes := map[string]AuditChange{}
+	for field, value := range old {
+		if !reflect.DeepEqual(value, current[field]) {
+			changes[field] = AuditChange{Before: value, After: current[field]}
+		}
+	}
+	for field, value := range current {
+		if _, ok := old[field]; !ok {
+			changes[field] = AuditChange{After: value}
+		}
+	}
+
+	encoded, err := json.Marshal(changes)
+	return string(encoded), err
+}
+
+// auditFields decodes the JSON representation of a snapshot into its top-level fields
+func auditFields(snapshot interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if snapshot == nil || reflect.ValueOf(snapshot).IsZero() {
+		return fields, nil
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(encoded, &fields)
+	return fields, err
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxCategoryPath is the length of the longest materialized path the path column can hold
+const MaxCategoryPath = 255
+
+// ErrCategoryCycle is returned when a category would be moved below itself or one of its descendants
+var ErrCategoryCycle = errors.New("category cannot be moved below itself or its descendants")
+
+// ErrCategoryTooDeep is returned when a category or one of its descendants would get a path longer than MaxCategoryPath
+var ErrCategoryTooDeep = errors.New("category tree would be nested too deeply")
+
+// Category is a node of the product category tree (e.g., Clothing > Shirts > T-Shirts)
+// The tree is stored as a materialized path: Path lists the IDs from the root down to the
+// category itself (e.g., "/1/4/9/"), so all descendants of a category are found with a
+// single prefix match instead of a recursive query
+type Category struct {
+	Id       uint       `json:"id"`                                                        // Primary key
+	Name     string     `json:"name" gorm:"size:191"`                                      // Display name
+	ParentId *uint      `json:"parent_id"`                                                 // Optional foreign key to the parent Category (nil for root categories)
+	Parent   *Category  `json:"-" gorm:"foreignKey:ParentId;constraint:OnDelete:RESTRICT"` // Parent category; a category with children cannot be deleted
+	Path     string     `json:"path" gorm:"size:255;index"`                                // Materialized path of IDs from the root (e.g., "/1/4/9/")
+	Depth    int        `json:"depth" gorm:"-"`                                            // Number of ancestors, computed virtual field
+	Children []Category `json:"children,omitempty" gorm:"-"`                               // Child categories, only populated for tree listings
+}
+
+// Tag is a free-form product label (e.g., "summer", "organic")
+// Products and tags are linked many-to-many through the product_tags join table
+type Tag struct {
+	Id   uint   `json:"id"`                               // Primary key
+	Name string `json:"name" gorm:"size:191;uniqueIndex"` // Unique tag name
+}
+
+// ProductTag represents the product_tags join table structure
+// Declares its foreign keys: deleting a product or a tag removes their links
+type ProductTag struct {
+	ProductId uint    `gorm:"primaryKey"`                  // Foreign key to products table
+	TagId     uint    `gorm:"primaryKey"`                  // Foreign key to tags table
+	Product   Product `gorm:"constraint:OnDelete:CASCADE"` // Tagged product
+	Tag       Tag     `gorm:"constraint:OnDelete:CASCADE"` // Assigned tag
+}
+
+// ChildPath returns the materialized path of a child category with the given ID
+// Root categories are children of the empty parent path
+func ChildPath(parentPath string, id uint) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// SetDepth computes Depth from the materialized path
+func (category *Category) SetDepth() {
+	category.Depth = strings.Count(category.Path, "/") - 2
+}
+
+// CategoryTree arranges categories into a forest ordered by path
+// categories must be ordered by path, so parents precede their children
+// Categories whose parent is missing from the list are returned as roots
+func CategoryTree(categories []Category) []Category {
+	// Attach children bottom-up, so every node is complete when it is copied into its parent
+	index := make(map[uint]int, len(categories))
+	for i := range categories {
+		categories[i].SetDepth()
+		index[categories[i].Id] = i
+	}
+
+	var roots []Category
+	for i := len(categories) - 1; i >= 0; i-- {
+		category := categories[i]
+		if category.ParentId != nil {
+			if parent, ok := index[*category.ParentId]; ok {
+				categories[parent].Children = append([]Category{category}, categories[parent].Children...)
+				continue
+			}
+		}
+		roots = append([]Category{category}, roots...)
+	}
+	if roots == nil {
+		roots = []Category{}
+	}
+	return roots
+}
+
+// MoveCategory moves a category below a new parent (nil for the root level) and rewrites the
+// materialized paths of the category and all of its descendants
+// Must run in a transaction in which category was read FOR UPDATE; the new parent is read
+// FOR UPDATE as well, so concurrent moves are serialized and cannot create a cycle
+// Returns ErrCategoryCycle if the new parent is the category itself or one of its descendants,
+// and ErrCategoryTooDeep if a path of the moved subtree would exceed MaxCategoryPath
+func MoveCategory(db *gorm.DB, category *Category, parentId *uint) error {
+	parentPath := ""
+	if parentId != nil {
+		var parent Category
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *parentId).First(&parent).Error; err != nil {
+			return err
+		}
+		if strings.HasPrefix(parent.Path, category.Path) {
+			return ErrCategoryCycle
+		}
+		parentPath = parent.Path
+	}
+
+	oldPath := category.Path
+	newPath := ChildPath(parentPath, category.Id)
+
+	// The deepest descendant grows by the same amount as the category's own path
+	var longest int
+	err := db.Model(&Category{}).Select("COALESCE(MAX(CHAR_LENGTH(path)), 0)").Where("path LIKE ?", oldPath+"%").Scan(&longest).Error
+	if err != nil {
+		return err
+	}
+	if longest-len(oldPath)+len(newPath) > MaxCategoryPath {
+		return ErrCategoryTooDeep
+	}
+
+	if err := db.Model(category).Update("parent_id", parentId).Error; err != nil {
+		return err
+	}
+
+	// Replace the old path prefix of the category and its descendants in one statement
+	err = db.Model(&Category{}).
+		Where("path LIKE ?", oldPath+"%").
+		Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newPath, len(oldPath)+1)).Error
+	if err != nil {
+		return err
+	}
+
+	category.ParentId = parentId
+	category.Path = newPath
+	return nil
+}
+
+// InCategory returns a GORM scope restricting products to a category and all of its descendants
+// Usage: database.DB.Scopes(models.InCategory(category)).Find(&products)
+func InCategory(category Category) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("products.category_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&Category{}).Select("id").Where("path LIKE ?", category.Path+"%"))
+	}
+}
+
+// WithTag returns a GORM scope restricting products to those carrying a tag
+// Usage: database.DB.Scopes(models.WithTag(3)).Find(&products)
+func WithTag(tagId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("products.id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&ProductTag{}).Select("product_id").Where("tag_id = ?", tagId))
+	}
+}
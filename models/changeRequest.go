@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions that can be placed under four-eyes approval
+// Named after the permission the original request requires
+const (
+	ChangeDeleteUser = "users:delete" // DELETE /api/users/:id
+	ChangeDeleteRole = "roles:delete" // DELETE /api/roles/:id
+	ChangeUpdateRole = "roles:update" // PUT /api/roles/:id
+)
+
+// Change request statuses
+const (
+	ChangePending  = "pending"  // Waiting for a second user to approve or reject it
+	ChangeApproved = "approved" // Approved and applied
+	ChangeRejected = "rejected" // Rejected, never applied
+	ChangeExpired  = "expired"  // Not reviewed before ExpiresAt, never applied
+)
+
+// ChangeRequest is a destructive admin action held back until a second user approves it
+// The parameters of the original request are stored in Payload so the action can be
+// applied exactly as requested once approved
+type ChangeRequest struct {
+	Id          uint       `json:"id"`                                                           // Primary key
+	Action      string     `json:"action" gorm:"size:64"`                                        // Requested action (e.g., "roles:delete")
+	TargetId    uint       `json:"target_id"`                                                    // ID of the user or role the action applies to
+	Payload     string     `json:"payload" gorm:"type:text"`                                     // JSON parameters of the original request
+	Status      string     `json:"status" gorm:"size:16;index"`                                  // One of pending, approved, rejected, expired
+	Reason      string     `json:"reason"`                                                       // Optional comment given when rejecting
+	RequestedBy *uint      `json:"requested_by"`                                                 // User who made the original request (nil once that user is deleted)
+	ReviewedBy  *uint      `json:"reviewed_by"`                                                  // User who approved or rejected the request
+	Requester   *User      `json:"-" gorm:"foreignKey:RequestedBy;constraint:OnDelete:SET NULL"` // Requesting user
+	Reviewer    *User      `json:"-" gorm:"foreignKey:ReviewedBy;constraint:OnDelete:SET NULL"`  // Reviewing user
+	CreatedAt   time.Time  `json:"created_at"`                                                   // Time the request was made
+	ReviewedAt  *time.Time `json:"reviewed_at"`                                                  // Time the request was approved or rejected
+	ExpiresAt   time.Time  `json:"expires_at"`                                                   // Time after which the request can no longer be approved
+}
+
+// Count implements the Entity interface for ChangeRequest
+// Returns the total number of change requests matching the conditions on db
+// Used by the Paginate function for pagination metadata
+func (request *ChangeRequest) Count(db *gorm.DB) (int64, error) {
+	var total int64
+	err := db.Model(&ChangeRequest{}).Count(&total).Error
+	return total, err
+}
+
+// Take implements the Entity interface for ChangeRequest
+// Retrieves a paginated subset of change requests, most recent first
+func (request *ChangeRequest) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
+	var requests []ChangeRequest
+	err := db.Order("created_at desc").Offset(offset).Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// ExpireChangeRequests marks pending change requests whose expiry has passed as expired
+func ExpireChangeRequests(db *gorm.DB, now time.Time) error {
+	return db.Model(&ChangeRequest{}).
+		Where("status = ? AND expires_at <= ?", ChangePending, now).
+		Update("status", ChangeExpired).Error
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dashboard holds the headline numbers shown on the admin landing page
+// Computed with a handful of aggregate queries instead of loading records
+type Dashboard struct {
+	TotalUsers        int64         `json:"total_users"`         // Number of user accounts, including pending invitations
+	NewUsers          int64         `json:"new_users"`           // Accounts created since Since
+	Products          int64         `json:"products"`            // Number of products in the catalog
+	Orders            int64         `json:"orders"`              // Number of orders
+	Revenue           float64       `json:"revenue"`             // Sum of (price * quantity) over all order items
+	AverageOrderValue float64       `json:"average_order_value"` // Revenue / Orders (0 without orders)
+	TopCustomers      []TopCustomer `json:"top_customers"`       // Customers with the highest revenue, best first
+	Since             time.Time     `json:"since"`               // Start of the period NewUsers is counted over
+	GeneratedAt       time.Time     `json:"generated_at"`        // Time the numbers were computed (they may be cached)
+}
+
+// TopCustomer summarizes the orders placed with one customer email address
+type TopCustomer struct {
+	Name    string  `json:"name"`    // Customer name given on their orders
+	Email   string  `json:"email"`   // Customer email address, identifying the customer
+	Orders  int64   `json:"orders"`  // Number of orders placed
+	Revenue float64 `json:"revenue"` // Sum of (price * quantity) over their order items
+}
+
+// MaskFields implements the FieldMasker interface for TopCustomer
+// Masks the customer email address unless the caller may read orders.email
+func (customer *TopCustomer) MaskFields(readable func(field string) bool) {
+	if !readable("email") {
+		customer.Email = MaskEmail(customer.Email)
+	}
+}
+
+// DashboardSources are the queries the dashboard aggregates over
+// Each one may carry scopes (e.g., row-level policies) restricting the rows it counts
+type DashboardSources struct {
+	Users    *gorm.DB
+	Products *gorm.DB
+	Orders   *gorm.DB
+}
+
+// LoadDashboard computes the dashboard numbers
+// New users are counted from since; accounts created before users.created_at was recorded
+// are counted as existing users only. At most topCustomers customers are returned
+func LoadDashboard(sources DashboardSources, since time.Time, topCustomers int) (Dashboard, error) {
+	dashboard := Dashboard{
+		Since:        since,
+		GeneratedAt:  time.Now(),
+		TopCustomers: []TopCustomer{},
+	}
+
+	// Count all users and the new ones in a single pass
+	var users struct {
+		Total  int64
+		Recent int64
+	}
+	err := sources.Users.Model(&User{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(users.created_at >= ?), 0) AS recent", since).
+		Scan(&users).Error
+	if err != nil {
+		return dashboard, err
+	}
+	dashboard.TotalUsers = users.Total
+	dashboard.NewUsers = users.Recent
+
+	if err := sources.Products.Model(&Product{}).Count(&dashboard.Products).Error; err != nil {
+		return dashboard, err
+	}
+
+	// Count orders and sum their items in a single pass; orders without items still count
+	var orders struct {
+		Total   int64
+		Revenue float64
+	}
+	err = sources.Orders.Table("orders").
+		Select("COUNT(DISTINCT orders.id) AS total, COALESCE(SUM(order_items.price*order_items.quantity), 0) AS revenue").
+		Joins("LEFT JOIN order_items ON orders.id = order_items.order_id").
+		Scan(&orders).Error
+	if err != nil {
+		return dashboard, err
+	}
+	dashboard.Orders = orders.Total
+	dashboard.Revenue = orders.Revenue
+	if orders.Total > 0 {
+		dashboard.AverageOrderValue = orders.Revenue / float64(orders.Total)
+	}
+
+	// Rank customers by revenue; customers are identified by their email address
+	err = sources.Orders.Table("orders").
+		Select("orders.email, MAX(CONCAT(orders.first_name, ' ', orders.last_name)) AS name, " +
+			"COUNT(DISTINCT orders.id) AS orders, " +
+			"SUM(order_items.price*order_items.quantity) AS revenue").
+		Joins("JOIN order_items ON orders.id = order_items.order_id").
+		Group("orders.email").
+		Order("revenue DESC").
+		Limit(topCustomers).
+		Scan(&dashboard.TopCustomers).Error
+	return dashboard, err
+}
@@ -7,12 +7,13 @@ import "gorm.io/gorm"
 // This provides a consistent pagination pattern across different entity types
 type Entity interface {
 	// Count returns the total number of records in the database for this entity type
-	Count(db *gorm.DB) int64
+	// Returns the database error if the query fails
+	Count(db *gorm.DB) (int64, error)
 
 	// Take retrieves a paginated subset of records from the database
 	// Parameters:
 	//   - limit: maximum number of records to return per page
 	//   - offset: number of records to skip (for pagination)
-	// Returns: slice of entity instances
-	Take(db *gorm.DB, limit int, offset int) interface{}
+	// Returns: slice of entity instances, or the database error if the query fails
+	Take(db *gorm.DB, limit int, offset int) (interface{}, error)
 }
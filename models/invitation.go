@@ -0,0 +1,80 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation represents a pending invitation for an admin-created user account
+// The invitee receives a single-use, expiring link and chooses their own password
+// Only a SHA-256 hash of the token is stored so a database leak cannot be used to accept invitations
+type Invitation struct {
+	Id         uint       `json:"id"`                                                        // Primary key
+	UserId     uint       `json:"user_id" gorm:"index"`                                      // Foreign key to the invited User
+	User       User       `json:"user" gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"` // Associated invited user (invitation is removed with the user)
+	InvitedBy  uint       `json:"invited_by"`                                                // ID of the admin who sent the invitation
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`                              // SHA-256 hash of the invitation token (excluded from JSON)
+	ExpiresAt  time.Time  `json:"expires_at"`                                                // Time after which the invitation link stops working
+	AcceptedAt *time.Time `json:"accepted_at"`                                               // Time the invitee set their password (nil while pending)
+	RevokedAt  *time.Time `json:"revoked_at"`                                                // Time an admin revoked the invitation (nil unless revoked)
+	CreatedAt  time.Time  `json:"created_at"`                                                // Time the invitation was first created
+	UpdatedAt  time.Time  `json:"updated_at"`                                                // Time the invitation was last (re)sent
+}
+
+// NewToken generates a fresh random invitation token valid for the given duration
+// Stores the token hash and expiry on the invitation and returns the plain token,
+// which must be delivered to the invitee and is never persisted
+// Calling NewToken again (e.g., when resending) invalidates any previous token
+func (invitation *Invitation) NewToken(ttl time.Duration) (string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(buffer)
+	invitation.TokenHash = HashInvitationToken(token)
+	invitation.ExpiresAt = time.Now().Add(ttl)
+
+	return token, nil
+}
+
+// IsPending reports whether the invitation can still be accepted
+// An invitation is pending until it is accepted, revoked or expired
+func (invitation *Invitation) IsPending() bool {
+	return invitation.AcceptedAt == nil && invitation.RevokedAt == nil && time.Now().Before(invitation.ExpiresAt)
+}
+
+// HashInvitationToken returns the hex-encoded SHA-256 hash of an invitation token
+// Used both when issuing tokens and when looking them up on acceptance
+func HashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Count implements the Entity interface for Invitation
+// Returns the number of invitations that are neither accepted nor revoked
+// Used by the Paginate function for pagination metadata
+func (invitation *Invitation) Count(db *gorm.DB) (int64, error) {
+	var total int64
+	err := db.Model(&Invitation{}).Where("accepted_at IS NULL AND revoked_at IS NULL").Count(&total).Error
+	return total, err
+}
+
+// Take implements the Entity interface for Invitation
+// Retrieves a paginated subset of open invitations with the invited user preloaded
+// Expired invitations are included so they can be resent
+func (invitation *Invitation) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
+	var invitations []Invitation
+	err := db.Preload("User").Where("accepted_at IS NULL AND revoked_at IS NULL").Offset(offset).Limit(limit).Find(&invitations).Error
+	return invitations, err
+}
+
+// MaskFields implements the FieldMasker interface for Invitation
+// Masks the protected fields of the invited user
+func (invitation *Invitation) MaskFields(readable func(field string) bool) {
+	invitation.User.MaskFields(readable)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginEvent records a single login attempt against the system
+// Both successful and failed attempts are stored so dormant or attacked accounts can be identified
+// Failed attempts for unknown emails are stored with UserId 0
+type LoginEvent struct {
+	Id        uint      `json:"id"`                   // Primary key
+	UserId    uint      `json:"user_id" gorm:"index"` // Foreign key to User (0 when the email did not match any user)
+	Email     string    `json:"email"`                // Email address submitted with the attempt
+	Success   bool      `json:"success"`              // Whether the attempt was successful
+	Reason    string    `json:"reason"`               // Failure reason (e.g., "email_not_found", "incorrect_password"), empty on success
+	Ip        string    `json:"ip"`                   // Client IP address
+	UserAgent string    `json:"user_agent"`           // Client User-Agent header
+	CreatedAt time.Time `json:"created_at"`           // Time of the attempt
+}
+
+// Count implements the Entity interface for LoginEvent
+// Returns the total number of login events matching the conditions on db
+// Used by the Paginate function for pagination metadata
+func (event *LoginEvent) Count(db *gorm.DB) (int64, error) {
+	var total int64
+	err := db.Model(&LoginEvent{}).Count(&total).Error
+	return total, err
+}
+
+// Take implements the Entity interface for LoginEvent
+// Retrieves a paginated subset of login events, most recent first
+func (event *LoginEvent) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
+	var events []LoginEvent
+	err := db.Order("created_at desc").Offset(offset).Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MaskFields implements the FieldMasker interface for LoginEvent
+// Masks the submitted email address unless the caller may read users.email
+func (event *LoginEvent) MaskFields(readable func(field string) bool) {
+	if !readable("email") {
+		event.Email = MaskEmail(event.Email)
+	}
+}
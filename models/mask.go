@@ -0,0 +1,23 @@
+package models
+
+import "strings"
+
+// FieldMasker is implemented by models exposing fields listed in ProtectedFields
+// MaskFields replaces the value of every protected field for which readable returns false
+type FieldMasker interface {
+	MaskFields(readable func(field string) bool)
+}
+
+// MaskEmail hides the local part of an email address except its first character
+// Example: "jane.doe@example.com" becomes "j***@example.com"
+func MaskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	return local[:1] + "***@" + domain
+}
@@ -5,45 +5,52 @@ import "gorm.io/gorm"
 // Order represents a customer order in the e-commerce system
 // Contains customer information and maintains a one-to-many relationship with OrderItems
 type Order struct {
-	Id         uint        `json:"id"`                           // Primary key, unique order identifier
-	FirstName  string      `json:"-"`                            // Customer first name (not included in JSON response)
-	LastName   string      `json:"-"`                            // Customer last name (not included in JSON response)
-	Name       string      `json:"name" gorm:"-"`                // Computed full name (FirstName + LastName), virtual field
-	Email      string      `json:"email"`                        // Customer email address
-	Total      float32     `json:"total" gorm:"-"`               // Computed order total, virtual field
-	UpdateAt   string      `json:"update_at"`                    // Last update timestamp
-	CreateAt   string      `json:"create_at"`                    // Creation timestamp
+	Id         uint        `json:"id"`                                    // Primary key, unique order identifier
+	FirstName  string      `json:"-"`                                     // Customer first name (not included in JSON response)
+	LastName   string      `json:"-"`                                     // Customer last name (not included in JSON response)
+	Name       string      `json:"name" gorm:"-"`                         // Computed full name (FirstName + LastName), virtual field
+	Email      string      `json:"email"`                                 // Customer email address
+	AssigneeId *uint       `json:"assignee_id" gorm:"index"`              // Optional ID of the sales rep User responsible for the order
+	Total      float32     `json:"total" gorm:"-"`                        // Computed order total, virtual field
+	UpdateAt   string      `json:"update_at"`                             // Last update timestamp
+	CreateAt   string      `json:"create_at"`                             // Creation timestamp
 	OrderItems []OrderItem `json:"order_items" gorm:"foreignKey:OrderId"` // Associated order items
 }
 
 // OrderItem represents an individual product within an order
 // Each order can contain multiple order items, forming a one-to-many relationship
+// An item may reference the product variant that was sold; title and price are kept
+// as they were at the time of purchase, so the item survives changes to the variant
 type OrderItem struct {
-	Id           uint    `json:"id"`            // Primary key
-	OrderId      uint    `json:"order_id"`      // Foreign key to parent Order
-	ProductTitle string  `json:"product_title"` // Product name at time of purchase
-	Price        float32 `json:"price"`         // Product price at time of purchase
-	Quantity     uint    `json:"quantity"`      // Quantity of this product in the order
+	Id           uint     `json:"id"`                                                    // Primary key
+	OrderId      uint     `json:"order_id"`                                              // Foreign key to parent Order
+	ProductTitle string   `json:"product_title"`                                         // Product name at time of purchase
+	Price        float32  `json:"price"`                                                 // Product price at time of purchase
+	Quantity     uint     `json:"quantity"`                                              // Quantity of this product in the order
+	VariantId    *uint    `json:"variant_id" gorm:"index"`                               // Optional foreign key to the Variant sold
+	Variant      *Variant `json:"variant,omitempty" gorm:"constraint:OnDelete:SET NULL"` // Variant sold; deleting it keeps the item
 }
 
 // Count implements the Entity interface for Order
 // Returns the total number of order records in the database
 // Used by the Paginate function to calculate pagination metadata
-func (order *Order) Count(db *gorm.DB) int64 {
+func (order *Order) Count(db *gorm.DB) (int64, error) {
 	var total int64
-	db.Model(&Order{}).Count(&total)
-	return total
+	err := db.Model(&Order{}).Count(&total).Error
+	return total, err
 }
 
 // Take implements the Entity interface for Order
 // Retrieves a paginated subset of orders and computes derived fields
-// Preloads OrderItems to avoid N+1 query problem
+// Preloads OrderItems with their variants to avoid N+1 query problem
 // Calculates order total and full customer name for each order
-func (order *Order) Take(db *gorm.DB, limit int, offset int) interface{} {
+func (order *Order) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
 	var orders []Order
 
 	// Retrieve paginated orders with eagerly loaded order items
-	db.Preload("OrderItems").Offset(offset).Limit(limit).Find(&orders)
+	if err := db.Preload("OrderItems.Variant.Options.OptionType").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+		return nil, err
+	}
 
 	// Compute derived fields for each order
 	for i := range orders {
@@ -58,5 +65,13 @@ func (order *Order) Take(db *gorm.DB, limit int, offset int) interface{} {
 		orders[i].Name = orders[i].FirstName + " " + orders[i].LastName
 		orders[i].Total = total
 	}
-	return orders
+	return orders, nil
+}
+
+// MaskFields implements the FieldMasker interface for Order
+// Masks the customer email address unless the caller may read orders.email
+func (order *Order) MaskFields(readable func(field string) bool) {
+	if !readable("email") {
+		order.Email = MaskEmail(order.Email)
+	}
 }
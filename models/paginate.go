@@ -20,7 +20,8 @@ import (
 //   - fiber.Map containing:
 //     - "data": paginated records from the entity
 //     - "meta": pagination metadata (total, page, last_page)
-func Paginate(db *gorm.DB, entity Entity, page int) fiber.Map {
+//   - the database error if either query fails
+func Paginate(db *gorm.DB, entity Entity, page int) (fiber.Map, error) {
 	// Records per page (configurable - currently set to 5)
 	limit := 5
 
@@ -28,10 +29,16 @@ func Paginate(db *gorm.DB, entity Entity, page int) fiber.Map {
 	offset := (page - 1) * limit
 
 	// Retrieve paginated data using entity's Take method
-	data := entity.Take(db, limit, offset)
+	data, err := entity.Take(db, limit, offset)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get total record count using entity's Count method
-	total := entity.Count(db)
+	total, err := entity.Count(db)
+	if err != nil {
+		return nil, err
+	}
 
 	// Return standardized pagination response
 	return fiber.Map{
@@ -41,5 +48,5 @@ func Paginate(db *gorm.DB, entity Entity, page int) fiber.Map {
 			"page":      page,
 			"last_page": math.Ceil(float64(total) / float64(limit)), // Calculate total number of pages
 		},
-	}
+	}, nil
 }
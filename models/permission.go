@@ -1,9 +1,105 @@
 package models
 
+import "strings"
+
 // Permission represents a permission in the role-based access control (RBAC) system
 // Permissions define granular access rights that can be assigned to roles
-// Examples: "view_users", "edit_products", "delete_orders"
+// Names follow the "<resource>:<action>" convention (e.g., "users:read", "products:delete", "orders:export")
+// Legacy "view_<resource>"/"edit_<resource>" names are still honored during the transition
 type Permission struct {
-	Id   uint   `json:"id"`   // Primary key
-	Name string `json:"name"` // Permission identifier (e.g., "view_users", "edit_products")
+	Id   uint   `json:"id"`                               // Primary key
+	Name string `json:"name" gorm:"size:191;uniqueIndex"` // Unique permission identifier (e.g., "users:read", "products:create")
+}
+
+// EffectivePermissions describes everything a user is allowed to do
+// Computed with the same rules IsAuthorized applies, so clients can decide which
+// menu items and actions to show without guessing
+type EffectivePermissions struct {
+	Permissions []string            `json:"permissions"` // Permission names granted through the user's roles (including inherited ones)
+	Actions     map[string][]string `json:"actions"`     // Allowed actions per resource (e.g., "products": ["read", "create"])
+}
+
+// Resources guarded by permissions
+// Routes declare one of these together with an action; the resulting permission names
+// are created automatically at startup
+const (
+	ResourceUsers     = "users"     // User accounts and invitations
+	ResourceRoles     = "roles"     // Roles, permissions and row-level policies
+	ResourceProducts  = "products"  // Product catalog and uploads
+	ResourceOrders    = "orders"    // Orders, exports and sales analytics
+	ResourceApprovals = "approvals" // Four-eyes change requests
+	ResourceAudit     = "audit"     // Audit log of mutating admin actions
+	ResourceMetrics   = "metrics"   // Prometheus metrics endpoint
+	ResourceDashboard = "dashboard" // Landing page summary numbers
+)
+
+// Actions that can be granted on a resource
+// Permission names combine a resource and an action as "<resource>:<action>" (e.g., "products:create")
+const (
+	ActionRead    = "read"    // View lists and details (GET)
+	ActionCreate  = "create"  // Create new records (POST)
+	ActionUpdate  = "update"  // Modify existing records (PUT/PATCH)
+	ActionDelete  = "delete"  // Remove records (DELETE)
+	ActionExport  = "export"  // Export data (e.g., "orders:export")
+	ActionApprove = "approve" // Approve or reject change requests (e.g., "approvals:approve")
+)
+
+// resourceExtraActions lists non-CRUD actions supported by specific resources
+// Used when mapping legacy "edit_<resource>" permissions onto action permissions
+var resourceExtraActions = map[string][]string{
+	ResourceOrders: {ActionExport},
+}
+
+// ProtectedFields lists the fields of each resource that are masked in responses
+// unless the caller holds the field-level read permission (e.g., "users.email:read")
+var ProtectedFields = map[string][]string{
+	ResourceUsers:  {"email"},
+	ResourceOrders: {"email"},
+}
+
+// FieldPermissionName builds a field-level permission name
+// Example: FieldPermissionName("orders", "email", ActionRead) returns "orders.email:read"
+func FieldPermissionName(resource string, field string, action string) string {
+	return PermissionName(resource+"."+field, action)
+}
+
+// PermissionName builds an action-level permission name for a resource
+// Example: PermissionName("products", ActionDelete) returns "products:delete"
+func PermissionName(resource string, action string) string {
+	return resource + ":" + action
+}
+
+// LegacyPermissionNames returns the legacy "view_"/"edit_" permissions that still grant an action
+// Evaluated alongside action permissions during the transition to fine-grained permissions:
+//   - read is granted by "view_<resource>" or "edit_<resource>"
+//   - every other action is granted by "edit_<resource>"
+func LegacyPermissionNames(resource string, action string) []string {
+	if action == ActionRead {
+		return []string{"view_" + resource, "edit_" + resource}
+	}
+	return []string{"edit_" + resource}
+}
+
+// ActionPermissionNames maps a legacy permission name onto the action permissions it implies
+// Returns nil for names that are not legacy "view_<resource>"/"edit_<resource>" permissions
+// Example: "edit_orders" maps to orders:read, orders:create, orders:update, orders:delete, orders:export
+func ActionPermissionNames(legacy string) []string {
+	if resource, ok := strings.CutPrefix(legacy, "view_"); ok {
+		return []string{PermissionName(resource, ActionRead)}
+	}
+
+	if resource, ok := strings.CutPrefix(legacy, "edit_"); ok {
+		names := []string{
+			PermissionName(resource, ActionRead),
+			PermissionName(resource, ActionCreate),
+			PermissionName(resource, ActionUpdate),
+			PermissionName(resource, ActionDelete),
+		}
+		for _, action := range resourceExtraActions[resource] {
+			names = append(names, PermissionName(resource, action))
+		}
+		return names
+	}
+
+	return nil
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidPolicy is returned when a policy references an unsupported resource, field or value
+var ErrInvalidPolicy = errors.New("invalid row-level policy")
+
+// Policy is a row-level access rule attached to a role
+// Restricts which rows of a resource the role's users may see: a row is visible when its
+// Field equals Value. A role with several policies on the same resource requires all of them
+// Value is either a literal (e.g., "emea") or a reference to an attribute of the
+// requesting user (e.g., "$user.id", "$user.region")
+// Examples:
+//   - sales rep: { resource: "orders", field: "assignee_id", value: "$user.id" }
+//   - regional manager: { resource: "users", field: "region", value: "$user.region" }
+type Policy struct {
+	Id       uint   `json:"id"`                   // Primary key
+	RoleId   uint   `json:"role_id" gorm:"index"` // Foreign key to the Role the policy applies to
+	Resource string `json:"resource"`             // Restricted resource (e.g., "orders", "users")
+	Field    string `json:"field"`                // Column compared against Value (e.g., "assignee_id", "region")
+	Value    string `json:"value"`                // Literal value or "$user.<attribute>" reference
+}
+
+// PolicyFields lists, per resource, the columns that policies may filter on
+// Acts as a whitelist so policy fields can be safely used as column names
+var PolicyFields = map[string][]string{
+	ResourceOrders: {"assignee_id", "email"},
+	ResourceUsers:  {"id", "region"},
+}
+
+// PolicyAttributes lists the attributes of the requesting user that policy values may reference
+var PolicyAttributes = []string{"id", "email", "region"}
+
+// Validate checks that the policy targets a supported resource, field and user attribute
+func (policy *Policy) Validate() error {
+	fields, ok := PolicyFields[policy.Resource]
+	if !ok || !slices.Contains(fields, policy.Field) {
+		return ErrInvalidPolicy
+	}
+
+	if attribute, ok := policy.Attribute(); ok && !slices.Contains(PolicyAttributes, attribute) {
+		return ErrInvalidPolicy
+	}
+	return nil
+}
+
+// Attribute returns the user attribute referenced by the policy value ("$user.<attribute>")
+// The second result is false when the value is a literal
+func (policy *Policy) Attribute() (string, bool) {
+	return strings.CutPrefix(policy.Value, "$user.")
+}
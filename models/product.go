@@ -4,28 +4,51 @@ import "gorm.io/gorm"
 
 // Product represents a product in the e-commerce catalog
 // Used for managing product inventory and details
+// Stock is only changed by recording a StockMovement, never written directly
 type Product struct {
-	Id          uint    `json:"id"`          // Primary key
-	Title       string  `json:"title"`       // Product name or title
-	Description string  `json:"description"` // Detailed product description
-	Image       string  `json:"image"`       // Product image URL or file path
-	Price       float64 `json:"price"`       // Product price (decimal format)
+	Id          uint      `json:"id"`                                                     // Primary key
+	Title       string    `json:"title"`                                                  // Product name or title
+	Description string    `json:"description"`                                            // Detailed product description
+	Image       string    `json:"image"`                                                  // Product image URL or file path
+	Price       float64   `json:"price"`                                                  // Product price (decimal format)
+	Stock       int       `json:"stock" gorm:"not null;default:0"`                        // Units on hand, the sum of the product's stock movements
+	LowStock    bool      `json:"low_stock" gorm:"-"`                                     // Whether Stock is at or below the low-stock threshold, virtual field
+	CategoryId  *uint     `json:"category_id" gorm:"index"`                               // Optional foreign key to the Category the product is filed under
+	Category    *Category `json:"category,omitempty" gorm:"constraint:OnDelete:SET NULL"` // Category, deleting it leaves the product uncategorized
+	Tags        []Tag     `json:"tags" gorm:"many2many:product_tags"`                     // Associated tags
+	TagIds      []uint    `json:"tag_ids,omitempty" gorm:"-"`                             // Tag IDs to assign on create/update, virtual input field
+}
+
+// FlagLowStock sets LowStock when the product's stock is at or below threshold
+func (product *Product) FlagLowStock(threshold int) {
+	product.LowStock = product.Stock <= threshold
+}
+
+// AssignTags converts TagIds into Tags references for association writes
+// Only the IDs are set, so callers should Omit("Tags.*") to avoid upserting tag rows
+func (product *Product) AssignTags() {
+	product.Tags = make([]Tag, len(product.TagIds))
+	for i, tagId := range product.TagIds {
+		product.Tags[i] = Tag{
+			Id: tagId,
+		}
+	}
 }
 
 // Count implements the Entity interface for Product
 // Returns the total number of product records in the database
 // Used by the Paginate function for pagination metadata
-func (product *Product) Count(db *gorm.DB) int64 {
+func (product *Product) Count(db *gorm.DB) (int64, error) {
 	var total int64
-	db.Model(&Product{}).Count(&total)
-	return total
+	err := db.Model(&Product{}).Count(&total).Error
+	return total, err
 }
 
 // Take implements the Entity interface for Product
 // Retrieves a paginated subset of products from the database
-// Returns products ordered by their primary key
-func (product *Product) Take(db *gorm.DB, limit int, offset int) interface{} {
+// Returns products ordered by their primary key, with their category and tags preloaded
+func (product *Product) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
 	var products []Product
-	db.Offset(offset).Limit(limit).Find(&products)
-	return products
+	err := db.Preload("Category").Preload("Tags").Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
 }
@@ -1,10 +1,100 @@
 package models
 
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleCycle is returned when a parent assignment would make a role inherit from itself
+var ErrRoleCycle = errors.New("role inheritance cycle detected")
+
 // Role represents a user role in the role-based access control (RBAC) system
 // Roles group multiple permissions together and are assigned to users
 // Maintains a many-to-many relationship with Permissions via the role_permissions join table
+// A role may optionally inherit every permission of a parent role (e.g., Viewer ⊂ Editor ⊂ Admin)
+// Row-level Policies further restrict which rows of a resource the role can access
 type Role struct {
-	Id          uint         `json:"id"`          // Primary key
-	Name        string       `json:"name"`        // Role name (e.g., "admin", "editor", "viewer")
-	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"` // Associated permissions
+	Id                   uint         `json:"id"`                                                            // Primary key
+	Name                 string       `json:"name"`                                                          // Role name (e.g., "admin", "editor", "viewer")
+	ParentId             *uint        `json:"parent_id"`                                                     // Optional foreign key to the parent Role
+	Parent               *Role        `json:"-" gorm:"foreignKey:ParentId;constraint:OnDelete:SET NULL"`     // Parent role whose permissions are inherited
+	Permissions          []Permission `json:"permissions" gorm:"many2many:role_permissions"`                 // Directly assigned permissions
+	InheritedPermissions []Permission `json:"inherited_permissions,omitempty" gorm:"-"`                      // Permissions inherited from ancestors, computed virtual field
+	Policies             []Policy     `json:"policies" gorm:"foreignKey:RoleId;constraint:OnDelete:CASCADE"` // Row-level policies restricting the role (not inherited)
+}
+
+// RolePermission represents the role_permissions join table structure
+// Used for direct manipulation of the many-to-many relationship and to declare its foreign keys:
+// deleting a role removes its grants, while a permission still granted to a role cannot be deleted
+type RolePermission struct {
+	RoleId       uint       `gorm:"primaryKey"`                   // Foreign key to roles table
+	PermissionId uint       `gorm:"primaryKey"`                   // Foreign key to permissions table
+	Role         Role       `gorm:"constraint:OnDelete:CASCADE"`  // Granting role
+	Permission   Permission `gorm:"constraint:OnDelete:RESTRICT"` // Granted permission
+}
+
+// Ancestors returns the chain of parent roles, nearest first
+// Returns ErrRoleCycle if the chain loops back on itself
+func (role *Role) Ancestors(db *gorm.DB) ([]Role, error) {
+	var ancestors []Role
+	visited := map[uint]bool{role.Id: true}
+
+	parentId := role.ParentId
+	for parentId != nil {
+		if visited[*parentId] {
+			return nil, ErrRoleCycle
+		}
+		visited[*parentId] = true
+
+		var parent Role
+		if err := db.Preload("Permissions").Where("id = ?", *parentId).First(&parent).Error; err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, parent)
+		parentId = parent.ParentId
+	}
+	return ancestors, nil
+}
+
+// LoadInheritedPermissions fills InheritedPermissions with the permissions of all ancestors
+// Permissions that are also assigned directly, or inherited through several levels, appear only once
+func (role *Role) LoadInheritedPermissions(db *gorm.DB) error {
+	ancestors, err := role.Ancestors(db)
+	if err != nil {
+		return err
+	}
+
+	seen := map[uint]bool{}
+	for _, permission := range role.Permissions {
+		seen[permission.Id] = true
+	}
+
+	role.InheritedPermissions = []Permission{}
+	for _, ancestor := range ancestors {
+		for _, permission := range ancestor.Permissions {
+			if !seen[permission.Id] {
+				seen[permission.Id] = true
+				role.InheritedPermissions = append(role.InheritedPermissions, permission)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateParent checks that roleId may inherit from parentId
+// Walks up the hierarchy starting at parentId and returns ErrRoleCycle if roleId is reached
+// Returns gorm.ErrRecordNotFound if any role in the chain does not exist
+// Use roleId 0 for roles that have not been created yet
+func ValidateParent(db *gorm.DB, roleId uint, parentId uint) error {
+	if roleId != 0 && roleId == parentId {
+		return ErrRoleCycle
+	}
+
+	role := Role{
+		Id:       roleId,
+		ParentId: &parentId,
+	}
+	_, err := role.Ancestors(db)
+	return err
 }
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mockDB returns a GORM connection backed by sqlmock
+// Expectations are matched in the order they are declared
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+// expectParent expects the queries loading a parent role without permissions
+// A nil grandparentId ends the chain; a missing role is expected with expectMissingParent
+func expectParent(mock sqlmock.Sqlmock, roleId uint, grandparentId *uint) {
+	mock.ExpectQuery("FROM `roles`").WithArgs(roleId, 1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(roleId, "role", grandparentId))
+	mock.ExpectQuery("FROM `role_permissions`").WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}))
+}
+
+// expectMissingParent expects a parent role lookup that finds nothing
+func expectMissingParent(mock sqlmock.Sqlmock, roleId uint) {
+	mock.ExpectQuery("FROM `roles`").WithArgs(roleId, 1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "parent_id"}))
+}
+
+// id returns a pointer to a role ID
+func id(roleId uint) *uint {
+	return &roleId
+}
+
+// TestValidateParent checks the parent assignments ValidateParent accepts and refuses
+// The hierarchy is walked upwards from the parent, one query per role
+func TestValidateParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		roleId   uint
+		parentId uint
+		expect   func(mock sqlmock.Sqlmock)
+		want     error
+	}{
+		{
+			name:     "self parent",
+			roleId:   1,
+			parentId: 1,
+			expect:   func(sqlmock.Sqlmock) {},
+			want:     ErrRoleCycle,
+		},
+		{
+			name:     "cycle through a descendant",
+			roleId:   1,
+			parentId: 3,
+			expect: func(mock sqlmock.Sqlmock) {
+				// 3 inherits from 2, which inherits from 1
+				expectParent(mock, 3, id(2))
+				expectParent(mock, 2, id(1))
+			},
+			want: ErrRoleCycle,
+		},
+		{
+			name:     "missing parent",
+			roleId:   1,
+			parentId: 9,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectMissingParent(mock, 9)
+			},
+			want: gorm.ErrRecordNotFound,
+		},
+		{
+			name:     "missing ancestor",
+			roleId:   1,
+			parentId: 2,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, 2, id(9))
+				expectMissingParent(mock, 9)
+			},
+			want: gorm.ErrRecordNotFound,
+		},
+		{
+			name:     "valid chain",
+			roleId:   1,
+			parentId: 2,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, 2, id(3))
+				expectParent(mock, 3, nil)
+			},
+		},
+		{
+			name:     "new role",
+			roleId:   0,
+			parentId: 2,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, 2, nil)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := mockDB(t)
+			test.expect(mock)
+
+			if err := ValidateParent(db, test.roleId, test.parentId); !errors.Is(err, test.want) {
+				t.Fatalf("ValidateParent(%d, %d) = %v, want %v", test.roleId, test.parentId, err, test.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestAncestors checks that Ancestors returns the parent chain nearest first,
+// and detects cycles that do not pass through the role itself
+func TestAncestors(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   Role
+		expect func(mock sqlmock.Sqlmock)
+		want   []uint
+		err    error
+	}{
+		{
+			name:   "no parent",
+			role:   Role{Id: 1},
+			expect: func(sqlmock.Sqlmock) {},
+			want:   []uint{},
+		},
+		{
+			name: "chain",
+			role: Role{Id: 1, ParentId: id(2)},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectParent(mock, 2, id(3))
+				expectParent(mock, 3, nil)
+			},
+			want: []uint{2, 3},
+		},
+		{
+			name: "cycle above the role",
+			role: Role{Id: 1, ParentId: id(2)},
+			expect: func(mock sqlmock.Sqlmock) {
+				// 2 and 3 inherit from each other
+				expectParent(mock, 2, id(3))
+				expectParent(mock, 3, id(2))
+			},
+			err: ErrRoleCycle,
+		},
+		{
+			name: "missing parent",
+			role: Role{Id: 1, ParentId: id(9)},
+			expect: func(mock sqlmock.Sqlmock) {
+				expectMissingParent(mock, 9)
+			},
+			err: gorm.ErrRecordNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := mockDB(t)
+			test.expect(mock)
+
+			ancestors, err := test.role.Ancestors(db)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Ancestors() error = %v, want %v", err, test.err)
+			}
+
+			got := []uint{}
+			for _, ancestor := range ancestors {
+				got = append(got, ancestor.Id)
+			}
+			if test.err == nil && !slices.Equal(got, test.want) {
+				t.Fatalf("Ancestors() = %v, want %v", got, test.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
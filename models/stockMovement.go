@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stock movement types
+const (
+	StockReceipt    = "receipt"    // Goods received from a supplier (increases stock)
+	StockSale       = "sale"       // Goods sold to a customer (decreases stock)
+	StockAdjustment = "adjustment" // Manual correction after a count, damage or loss (either direction)
+	StockReturn     = "return"     // Goods returned by a customer (increases stock)
+)
+
+// ErrInvalidStockChange is returned by StockChange for a quantity that does not fit the movement type
+var ErrInvalidStockChange = errors.New("quantity must be positive, or non-zero for adjustments")
+
+// StockMovement is an entry of the append-only stock ledger
+// Every change of Product.Stock or Variant.Stock is recorded as a movement in the same transaction,
+// so the stock level of a product equals the sum of its movements without a variant, and the
+// stock level of a variant the sum of its movements
+// Movements are never updated or deleted, not even with their product or variant, so no history is lost
+type StockMovement struct {
+	Id         uint      `json:"id"`                                                       // Primary key
+	ProductId  uint      `json:"product_id" gorm:"index:idx_stock_movement_product"`       // Foreign key to Product
+	VariantId  *uint     `json:"variant_id" gorm:"index"`                                  // Foreign key to the Variant whose stock moved (nil for product stock)
+	Type       string    `json:"type" gorm:"size:16"`                                      // One of receipt, sale, adjustment, return
+	Quantity   int       `json:"quantity"`                                                 // Signed stock change (negative for sales and downward adjustments)
+	StockAfter int       `json:"stock_after"`                                              // Stock level of the product after the movement
+	Reason     string    `json:"reason"`                                                   // Free-form explanation (e.g., supplier delivery note, stocktake)
+	ActorId    *uint     `json:"actor_id" gorm:"index"`                                    // User who recorded the movement
+	Product    Product   `json:"-" gorm:"constraint:OnDelete:RESTRICT"`                    // Moved product; one with recorded movements cannot be deleted
+	Variant    *Variant  `json:"-" gorm:"constraint:OnDelete:RESTRICT"`                    // Moved variant; one with recorded movements cannot be deleted
+	Actor      *User     `json:"-" gorm:"foreignKey:ActorId;constraint:OnDelete:SET NULL"` // Recording user
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_stock_movement_product"`       // Time of the movement
+}
+
+// StockChange returns the signed stock change of a movement of the given type
+// Receipts, sales and returns take a positive quantity (a sale subtracts it);
+// adjustments take a non-zero quantity that is applied as is
+// Fails with ErrInvalidStockChange for a quantity that does not fit the type
+func StockChange(movementType string, quantity int) (int, error) {
+	switch movementType {
+	case StockReceipt, StockReturn:
+		if quantity > 0 {
+			return quantity, nil
+		}
+	case StockSale:
+		if quantity > 0 {
+			return -quantity, nil
+		}
+	case StockAdjustment:
+		if quantity != 0 {
+			return quantity, nil
+		}
+	}
+	return 0, ErrInvalidStockChange
+}
+
+// Count implements the Entity interface for StockMovement
+// Returns the total number of stock movements matching the conditions on db
+// Used by the Paginate function for pagination metadata
+func (movement *StockMovement) Count(db *gorm.DB) (int64, error) {
+	var total int64
+	err := db.Model(&StockMovement{}).Count(&total).Error
+	return total, err
+}
+
+// Take implements the Entity interface for StockMovement
+// Retrieves a paginated subset of stock movements, most recent first
+func (movement *StockMovement) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
+	var movements []StockMovement
+	err := db.Order("created_at desc, id desc").Offset(offset).Limit(limit).Find(&movements).Error
+	return movements, err
+}
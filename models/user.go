@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -8,31 +10,90 @@ import (
 // User represents a user account in the system
 // Maintains authentication credentials and role-based access control
 type User struct {
-	Id        uint   `json:"id"`         // Primary key
-	FirstName string `json:"first_name"` // User's first name
-	LastName  string `json:"last_name"`  // User's last name
-	Email     string `json:"email" gorm:"unique"` // Email address (unique constraint)
-	Password  []byte `json:"-"`          // Hashed password (excluded from JSON for security)
-	RoleId    uint   `json:"role_id"`    // Foreign key to Role
-	Role      Role   `json:"role" gorm:"foreignKey:RoleId"` // Associated role
+	Id          uint                  `json:"id"`                                // Primary key
+	FirstName   string                `json:"first_name"`                        // User's first name
+	LastName    string                `json:"last_name"`                         // User's last name
+	Email       string                `json:"email" gorm:"unique"`               // Email address (unique constraint)
+	Password    []byte                `json:"-"`                                 // Hashed password (excluded from JSON for security)
+	Roles       []Role                `json:"roles" gorm:"many2many:user_roles"` // Associated roles (effective permissions are their union)
+	RoleIds     []uint                `json:"role_ids,omitempty" gorm:"-"`       // Role IDs to assign on create/update, virtual input field
+	LastLoginAt *time.Time            `json:"last_login_at"`                     // Time of the most recent successful login (nil if never logged in)
+	LastLoginIp string                `json:"last_login_ip"`                     // Client IP address of the most recent successful login
+	Pending     bool                  `json:"pending"`                           // Whether the account is waiting for its invitation to be accepted
+	Region      string                `json:"region" gorm:"index"`               // Sales region the user belongs to, used by row-level policies
+	CreatedAt   *time.Time            `json:"created_at"`                        // Time the account was created (nil for accounts predating the column)
+	Access      *EffectivePermissions `json:"access,omitempty" gorm:"-"`         // Resolved permissions, only populated for the current user's profile
+}
+
+// UserRole represents the user_roles join table structure
+// Declares its foreign keys: deleting a user removes their assignments,
+// while a role still assigned to a user cannot be deleted
+// An assignment may be time-bound: it only grants access between StartsAt and ExpiresAt
+// (either bound may be nil), and expired assignments are removed by a background sweeper
+type UserRole struct {
+	UserId           uint       `json:"user_id" gorm:"primaryKey"`                                  // Foreign key to users table
+	RoleId           uint       `json:"role_id" gorm:"primaryKey"`                                  // Foreign key to roles table
+	StartsAt         *time.Time `json:"starts_at"`                                                  // Time the assignment becomes active (nil = immediately)
+	ExpiresAt        *time.Time `json:"expires_at" gorm:"index"`                                    // Time the assignment stops granting access (nil = never)
+	GrantedBy        *uint      `json:"granted_by"`                                                 // Admin who granted a time-bound assignment, notified before it expires
+	ExpiryNotifiedAt *time.Time `json:"-"`                                                          // Time the granting admin was notified of the upcoming expiry
+	User             User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`                       // Assigned user
+	Role             Role       `json:"role" gorm:"constraint:OnDelete:RESTRICT"`                   // Assigned role
+	Granter          *User      `json:"-" gorm:"foreignKey:GrantedBy;constraint:OnDelete:SET NULL"` // Granting admin
+}
+
+// ActiveAt reports whether the assignment grants access at the given time
+func (userRole *UserRole) ActiveAt(t time.Time) bool {
+	if userRole.StartsAt != nil && t.Before(*userRole.StartsAt) {
+		return false
+	}
+	return userRole.ExpiresAt == nil || t.Before(*userRole.ExpiresAt)
 }
 
 // Count implements the Entity interface for User
 // Returns the total number of user records in the database
 // Used by the Paginate function for pagination metadata
-func (user *User) Count(db *gorm.DB) int64 {
+func (user *User) Count(db *gorm.DB) (int64, error) {
 	var total int64
-	db.Model(&User{}).Count(&total)
-	return total
+	err := db.Model(&User{}).Count(&total).Error
+	return total, err
 }
 
 // Take implements the Entity interface for User
-// Retrieves a paginated subset of users with their roles and permissions preloaded
-// Eagerly loads Role.Permissions to avoid N+1 query problem
-func (user *User) Take(db *gorm.DB, limit int, offset int) interface{} {
+// Retrieves a paginated subset of users with all their roles and permissions preloaded
+// Eagerly loads Roles.Permissions to avoid N+1 query problem
+func (user *User) Take(db *gorm.DB, limit int, offset int) (interface{}, error) {
 	var users []User
-	db.Preload("Role.Permissions").Offset(offset).Limit(limit).Find(&users)
-	return users
+	err := db.Preload("Roles.Permissions").Offset(offset).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// MaskFields implements the FieldMasker interface for User
+// Masks the email address unless the caller may read users.email
+func (user *User) MaskFields(readable func(field string) bool) {
+	if !readable("email") {
+		user.Email = MaskEmail(user.Email)
+	}
+}
+
+// AssignRoles converts RoleIds into Roles references for association writes
+// Only the IDs are set, so callers should Omit("Roles.*") to avoid upserting role rows
+func (user *User) AssignRoles() {
+	user.Roles = make([]Role, len(user.RoleIds))
+	for i, roleId := range user.RoleIds {
+		user.Roles[i] = Role{
+			Id: roleId,
+		}
+	}
+}
+
+// InactiveSince returns a GORM scope selecting users whose last successful login
+// happened before the given time, including users who have never logged in
+// Intended for use with db.Scopes(...) when searching for dormant accounts
+func InactiveSince(since time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("last_login_at IS NULL OR last_login_at < ?", since)
+	}
 }
 
 // SetPassword hashes a plain text password using bcrypt and stores it
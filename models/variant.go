@@ -0,0 +1,50 @@
+package models
+
+import "gorm.io/gorm"
+
+// OptionType is a dimension products vary in (e.g., "size", "color")
+// Variants give each option type they use a value (e.g., size "M", color "red")
+type OptionType struct {
+	Id   uint   `json:"id"`                              // Primary key
+	Name string `json:"name" gorm:"size:64;uniqueIndex"` // Unique option type name
+}
+
+// Variant is a sellable version of a product with its own SKU and stock (e.g., a shirt in size M, red)
+// The price overrides the product price when set; the image falls back to the product image when empty
+// Like Product.Stock, Stock is only changed by recording a StockMovement for the variant
+type Variant struct {
+	Id             uint            `json:"id"`                                                              // Primary key
+	ProductId      uint            `json:"product_id" gorm:"index"`                                         // Foreign key to the parent Product
+	Product        Product         `json:"-" gorm:"constraint:OnDelete:CASCADE"`                            // Parent product; deleting it removes its variants
+	Sku            string          `json:"sku" gorm:"size:64;uniqueIndex"`                                  // Unique stock keeping unit
+	Price          *float64        `json:"price"`                                                           // Price override (nil = product price)
+	Image          string          `json:"image"`                                                           // Variant image URL or file path (empty = product image)
+	Stock          int             `json:"stock" gorm:"not null;default:0"`                                 // Units on hand, the sum of the variant's stock movements
+	Options        []VariantOption `json:"options" gorm:"foreignKey:VariantId;constraint:OnDelete:CASCADE"` // Option values identifying the variant
+	EffectivePrice float64         `json:"effective_price" gorm:"-"`                                        // Price the variant sells at, virtual field
+	LowStock       bool            `json:"low_stock" gorm:"-"`                                              // Whether Stock is at or below the low-stock threshold, virtual field
+}
+
+// VariantOption is the value a variant gives an option type (e.g., size "M")
+// A variant has at most one value per option type
+type VariantOption struct {
+	VariantId    uint       `json:"-" gorm:"primaryKey"`                             // Foreign key to Variant
+	OptionTypeId uint       `json:"option_type_id" gorm:"primaryKey"`                // Foreign key to OptionType
+	OptionType   OptionType `json:"option_type" gorm:"constraint:OnDelete:RESTRICT"` // Option type; one still in use cannot be deleted
+	Value        string     `json:"value" gorm:"size:191"`                           // Option value (e.g., "M", "red")
+}
+
+// Resolve fills the virtual fields of a variant
+// The effective price is the override if set, otherwise the price of the product
+func (variant *Variant) Resolve(productPrice float64, lowStockThreshold int) {
+	variant.EffectivePrice = productPrice
+	if variant.Price != nil {
+		variant.EffectivePrice = *variant.Price
+	}
+	variant.LowStock = variant.Stock <= lowStockThreshold
+}
+
+// WithOptions preloads the option values of the variants loaded through db, with their option types
+func WithOptions(db *gorm.DB) *gorm.DB {
+	return db.Preload("Options.OptionType")
+}
@@ -0,0 +1,83 @@
+package rbac
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// DefaultFile is the policy file used when no path is given
+const DefaultFile = "rbac.yaml"
+
+// Command runs the rbac command line interface
+// Usage: go-admin rbac apply [--file rbac.yaml] [--dry-run]
+//   - apply: validates the policy file, prints the diff against the database and applies it
+//   - --dry-run: only prints the diff without changing the database
+//
+// Running servers are not notified: they keep serving cached permission sets until their
+// permission cache expires, which the command points out after applying changes
+func Command(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "apply" {
+		return errors.New("usage: go-admin rbac apply [--file rbac.yaml] [--dry-run]")
+	}
+
+	flags := flag.NewFlagSet("rbac apply", flag.ContinueOnError)
+	flags.SetOutput(out)
+	file := flags.String("file", DefaultFile, "path to the RBAC policy file")
+	dryRun := flags.Bool("dry-run", false, "print the diff without applying it")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	applied, err := syncPolicy(db, *file, *dryRun, out)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		fmt.Fprintln(out, "rbac: running servers keep serving cached permissions until PERMISSION_CACHE_TTL expires; restart them to apply the changes immediately")
+	}
+	return nil
+}
+
+// Sync loads the policy file, prints the planned changes to out and applies them unless dryRun is set
+// Used both by the command line and at server startup (RBAC_POLICY_FILE)
+func Sync(db *gorm.DB, path string, dryRun bool, out io.Writer) error {
+	_, err := syncPolicy(db, path, dryRun, out)
+	return err
+}
+
+// syncPolicy implements Sync and returns the number of changes applied
+func syncPolicy(db *gorm.DB, path string, dryRun bool, out io.Writer) (int, error) {
+	definition, err := Load(path)
+	if err != nil {
+		return 0, err
+	}
+
+	changes, err := Plan(db, definition)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "rbac: database is in sync with", path)
+		return 0, nil
+	}
+
+	for _, change := range changes {
+		fmt.Fprintln(out, change)
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "rbac: %d change(s) not applied (dry run)\n", len(changes))
+		return 0, nil
+	}
+
+	if err := Apply(db, changes); err != nil {
+		return 0, err
+	}
+	fmt.Fprintf(out, "rbac: applied %d change(s)\n", len(changes))
+	return len(changes), nil
+}
@@ -0,0 +1,107 @@
+package rbac
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is the declarative RBAC policy loaded from a YAML file
+// Lists the permissions that must exist and the roles with their parent and permission sets
+// Roles listed in the file are authoritative: their parent and direct permissions are synced
+// exactly as declared. Roles and permissions that are not listed are left untouched
+//
+// Example:
+//
+//	permissions: [products:read, products:create]
+//	roles:
+//	  - name: Viewer
+//	    permissions: [products:read]
+//	  - name: Editor
+//	    parent: Viewer
+//	    permissions: [products:create]
+type Definition struct {
+	Permissions []string   `yaml:"permissions"` // Permission names that must exist
+	Roles       []RoleSpec `yaml:"roles"`       // Managed roles
+}
+
+// RoleSpec declares a single managed role
+type RoleSpec struct {
+	Name        string   `yaml:"name"`        // Unique role name
+	Parent      string   `yaml:"parent"`      // Optional name of the role this role inherits from
+	Permissions []string `yaml:"permissions"` // Directly granted permission names (must be declared)
+}
+
+// Load reads and validates a policy file
+// Unknown keys are rejected so typos do not silently drop parts of the policy
+func Load(path string) (*Definition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var definition Definition
+	if err := decoder.Decode(&definition); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := definition.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &definition, nil
+}
+
+// Validate checks the definition for internal consistency:
+//   - permission and role names are non-empty and unique
+//   - roles only grant declared permissions
+//   - parents reference declared roles and do not form cycles
+func (definition *Definition) Validate() error {
+	permissions := map[string]bool{}
+	for _, name := range definition.Permissions {
+		if name == "" {
+			return fmt.Errorf("permission name must not be empty")
+		}
+		if permissions[name] {
+			return fmt.Errorf("permission %q is declared twice", name)
+		}
+		permissions[name] = true
+	}
+
+	roles := map[string]RoleSpec{}
+	for _, role := range definition.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("role name must not be empty")
+		}
+		if _, ok := roles[role.Name]; ok {
+			return fmt.Errorf("role %q is declared twice", role.Name)
+		}
+		roles[role.Name] = role
+
+		for _, permission := range role.Permissions {
+			if !permissions[permission] {
+				return fmt.Errorf("role %q grants undeclared permission %q", role.Name, permission)
+			}
+		}
+	}
+
+	for _, role := range definition.Roles {
+		// Walk up the hierarchy; revisiting a role means a cycle
+		visited := []string{role.Name}
+		for parent := role.Parent; parent != ""; parent = roles[parent].Parent {
+			if _, ok := roles[parent]; !ok {
+				return fmt.Errorf("role %q inherits from undeclared role %q", role.Name, parent)
+			}
+			if slices.Contains(visited, parent) {
+				return fmt.Errorf("role %q has an inheritance cycle", role.Name)
+			}
+			visited = append(visited, parent)
+		}
+	}
+	return nil
+}
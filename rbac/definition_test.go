@@ -0,0 +1,94 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidate checks the inconsistencies Validate rejects in a policy file
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition Definition
+		want       string // Substring of the expected error, empty if the definition is valid
+	}{
+		{
+			name: "valid hierarchy",
+			definition: Definition{
+				Permissions: []string{"products:read", "products:create"},
+				Roles: []RoleSpec{
+					{Name: "Viewer", Permissions: []string{"products:read"}},
+					{Name: "Editor", Parent: "Viewer", Permissions: []string{"products:create"}},
+					{Name: "Admin", Parent: "Editor"},
+				},
+			},
+		},
+		{
+			name:       "empty permission name",
+			definition: Definition{Permissions: []string{""}},
+			want:       "permission name must not be empty",
+		},
+		{
+			name:       "duplicate permission",
+			definition: Definition{Permissions: []string{"products:read", "products:read"}},
+			want:       `permission "products:read" is declared twice`,
+		},
+		{
+			name:       "empty role name",
+			definition: Definition{Roles: []RoleSpec{{Name: ""}}},
+			want:       "role name must not be empty",
+		},
+		{
+			name:       "duplicate role",
+			definition: Definition{Roles: []RoleSpec{{Name: "Viewer"}, {Name: "Viewer"}}},
+			want:       `role "Viewer" is declared twice`,
+		},
+		{
+			name: "undeclared permission",
+			definition: Definition{
+				Permissions: []string{"products:read"},
+				Roles:       []RoleSpec{{Name: "Editor", Permissions: []string{"products:read", "products:create"}}},
+			},
+			want: `role "Editor" grants undeclared permission "products:create"`,
+		},
+		{
+			name:       "unknown parent",
+			definition: Definition{Roles: []RoleSpec{{Name: "Editor", Parent: "Viewer"}}},
+			want:       `role "Editor" inherits from undeclared role "Viewer"`,
+		},
+		{
+			name:       "unknown grandparent",
+			definition: Definition{Roles: []RoleSpec{{Name: "Admin", Parent: "Editor"}, {Name: "Editor", Parent: "Viewer"}}},
+			want:       `role "Admin" inherits from undeclared role "Viewer"`,
+		},
+		{
+			name:       "self parent",
+			definition: Definition{Roles: []RoleSpec{{Name: "Editor", Parent: "Editor"}}},
+			want:       `role "Editor" has an inheritance cycle`,
+		},
+		{
+			name: "inheritance cycle",
+			definition: Definition{Roles: []RoleSpec{
+				{Name: "Viewer", Parent: "Admin"},
+				{Name: "Editor", Parent: "Viewer"},
+				{Name: "Admin", Parent: "Editor"},
+			}},
+			want: "has an inheritance cycle",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.definition.Validate()
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Validate() = %v, want an error containing %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"go-admin/middlewares"
+	"go-admin/models"
+	"go-admin/util"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartGrantSweeper runs SweepGrants every interval in a background goroutine
+// notice is how long before expiry the granting admin is emailed
+// Errors are logged; the sweeper keeps running
+func StartGrantSweeper(db *gorm.DB, interval time.Duration, notice time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			if err := SweepGrants(db, now, notice); err != nil {
+				util.Logger.Error("role grant sweep failed", "error", err)
+			}
+		}
+	}()
+}
+
+// SweepGrants notifies admins of time-bound role grants expiring within notice
+// and removes grants that have already expired
+// Expired grants stop granting access as soon as they expire regardless of the sweeper;
+// removing them keeps user_roles and role listings accurate
+// Expired grants are removed even if notifying fails; the errors of both steps are returned
+func SweepGrants(db *gorm.DB, now time.Time, notice time.Duration) error {
+	notifyErr := notifyExpiringGrants(db, now, notice)
+	return errors.Join(notifyErr, removeExpiredGrants(db, now))
+}
+
+// notifyExpiringGrants emails the granting admin of every grant expiring within notice
+// Each grant is notified once; a grant whose expiry is changed is notified again
+// A notice that cannot be sent is logged and retried on the next sweep, without holding up the others
+func notifyExpiringGrants(db *gorm.DB, now time.Time, notice time.Duration) error {
+	var grants []models.UserRole
+	err := db.Preload("User").Preload("Role").Preload("Granter").
+		Where("expires_at > ? AND expires_at <= ?", now, now.Add(notice)).
+		Where("granted_by IS NOT NULL AND expiry_notified_at IS NULL").
+		Find(&grants).Error
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		if grant.Granter == nil {
+			continue
+		}
+
+		body := fmt.Sprintf("The role %q you granted to %s %s (%s) expires at %s.\n\nExtend the grant before then if access is still needed.",
+			grant.Role.Name, grant.User.FirstName, grant.User.LastName, grant.User.Email, grant.ExpiresAt.Format(time.RFC1123))
+		if err := util.SendMail(grant.Granter.Email, "Role grant expiring soon", body); err != nil {
+			util.Logger.Error("role grant expiry notice failed", "user_id", grant.UserId, "role_id", grant.RoleId, "error", err)
+			continue
+		}
+
+		// Record the notification so the admin is not emailed again
+		err := db.Model(&models.UserRole{}).
+			Where("user_id = ? AND role_id = ?", grant.UserId, grant.RoleId).
+			Update("expiry_notified_at", now).Error
+		if err != nil {
+			util.Logger.Error("recording role grant expiry notice failed", "user_id", grant.UserId, "role_id", grant.RoleId, "error", err)
+		}
+	}
+	return nil
+}
+
+// removeExpiredGrants deletes expired role grants and drops the cached assignments of their users
+func removeExpiredGrants(db *gorm.DB, now time.Time) error {
+	var grants []models.UserRole
+	if err := db.Select("user_id", "role_id").Where("expires_at <= ?", now).Find(&grants).Error; err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		err := db.Where("user_id = ? AND role_id = ? AND expires_at <= ?", grant.UserId, grant.RoleId, now).
+			Delete(&models.UserRole{}).Error
+		if err != nil {
+			return err
+		}
+		middlewares.InvalidateUser(grant.UserId)
+	}
+	return nil
+}
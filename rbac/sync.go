@@ -0,0 +1,177 @@
+package rbac
+
+import (
+	"fmt"
+	"go-admin/models"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// Change is a single difference between the policy file and the database
+type Change struct {
+	Kind   string // "permission", "role", "parent", "grant" or "revoke"
+	Role   string // Affected role name (empty for permission changes)
+	Target string // Permission name, role name or parent name the change is about
+}
+
+// String renders the change as a line of a diff
+//   - "+" adds a permission, role or grant
+//   - "-" revokes a grant
+//   - "~" changes a role's parent
+func (change Change) String() string {
+	switch change.Kind {
+	case "permission":
+		return "+ permission " + change.Target
+	case "role":
+		return "+ role " + change.Target
+	case "parent":
+		parent := change.Target
+		if parent == "" {
+			parent = "(none)"
+		}
+		return "~ role " + change.Role + " parent " + parent
+	case "grant":
+		return "+ role " + change.Role + " grant " + change.Target
+	case "revoke":
+		return "- role " + change.Role + " revoke " + change.Target
+	}
+	return "? " + change.Kind
+}
+
+// Plan compares the definition with the database and returns the changes needed to sync it
+// An empty plan means the database already matches the policy file
+func Plan(db *gorm.DB, definition *Definition) ([]Change, error) {
+	var permissions []models.Permission
+	if err := db.Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	var roles []models.Role
+	if err := db.Preload("Permissions").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	existingPermissions := map[string]bool{}
+	for _, permission := range permissions {
+		existingPermissions[permission.Name] = true
+	}
+
+	existingRoles := map[string]models.Role{}
+	roleNames := map[uint]string{}
+	for _, role := range roles {
+		existingRoles[role.Name] = role
+		roleNames[role.Id] = role.Name
+	}
+
+	var changes []Change
+
+	for _, name := range definition.Permissions {
+		if !existingPermissions[name] {
+			changes = append(changes, Change{Kind: "permission", Target: name})
+		}
+	}
+
+	for _, spec := range definition.Roles {
+		role, exists := existingRoles[spec.Name]
+		if !exists {
+			changes = append(changes, Change{Kind: "role", Target: spec.Name})
+		}
+
+		// Compare parent by name
+		currentParent := ""
+		if role.ParentId != nil {
+			currentParent = roleNames[*role.ParentId]
+		}
+		if currentParent != spec.Parent {
+			changes = append(changes, Change{Kind: "parent", Role: spec.Name, Target: spec.Parent})
+		}
+
+		// Compare direct permission grants
+		var current []string
+		for _, permission := range role.Permissions {
+			current = append(current, permission.Name)
+		}
+		for _, name := range spec.Permissions {
+			if !slices.Contains(current, name) {
+				changes = append(changes, Change{Kind: "grant", Role: spec.Name, Target: name})
+			}
+		}
+		for _, name := range current {
+			if !slices.Contains(spec.Permissions, name) {
+				changes = append(changes, Change{Kind: "revoke", Role: spec.Name, Target: name})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Apply executes a plan in a single transaction
+// Permissions and roles are created first so parents and grants can reference them by name
+func Apply(db *gorm.DB, changes []Change) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			switch change.Kind {
+			case "permission":
+				if err := tx.Create(&models.Permission{Name: change.Target}).Error; err != nil {
+					return err
+				}
+			case "role":
+				if err := tx.Create(&models.Role{Name: change.Target}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, change := range changes {
+			var err error
+			switch change.Kind {
+			case "parent":
+				err = setParent(tx, change.Role, change.Target)
+			case "grant":
+				err = setGrant(tx, change.Role, change.Target, true)
+			case "revoke":
+				err = setGrant(tx, change.Role, change.Target, false)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", change, err)
+			}
+		}
+		return nil
+	})
+}
+
+// setParent points a role at the named parent, or clears it when parent is empty
+func setParent(tx *gorm.DB, roleName string, parentName string) error {
+	var parentId *uint
+	if parentName != "" {
+		var parent models.Role
+		if err := tx.Where("name = ?", parentName).First(&parent).Error; err != nil {
+			return err
+		}
+		parentId = &parent.Id
+	}
+	return tx.Model(&models.Role{}).Where("name = ?", roleName).Update("parent_id", parentId).Error
+}
+
+// setGrant adds or removes the role_permissions row linking a role and a permission by name
+func setGrant(tx *gorm.DB, roleName string, permissionName string, grant bool) error {
+	var role models.Role
+	if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return err
+	}
+
+	var permission models.Permission
+	if err := tx.Where("name = ?", permissionName).First(&permission).Error; err != nil {
+		return err
+	}
+
+	rolePermission := models.RolePermission{
+		RoleId:       role.Id,
+		PermissionId: permission.Id,
+	}
+	if grant {
+		return tx.Create(&rolePermission).Error
+	}
+	return tx.Where(&rolePermission).Delete(&models.RolePermission{}).Error
+}
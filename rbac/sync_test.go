@@ -0,0 +1,161 @@
+package rbac
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// storedRole is a role row together with the names of its directly granted permissions
+type storedRole struct {
+	id          uint
+	name        string
+	parentId    *uint
+	permissions []string
+}
+
+// mockDB returns a GORM connection backed by sqlmock
+// Expectations are matched in the order they are declared
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+// expectStored expects the queries Plan issues to load the stored permissions and roles
+func expectStored(mock sqlmock.Sqlmock, permissions []string, roles []storedRole) {
+	permissionIds := map[string]int{}
+	permissionRows := sqlmock.NewRows([]string{"id", "name"})
+	for i, name := range permissions {
+		permissionIds[name] = i + 1
+		permissionRows.AddRow(i+1, name)
+	}
+	mock.ExpectQuery("FROM `permissions`").WillReturnRows(permissionRows)
+
+	roleRows := sqlmock.NewRows([]string{"id", "name", "parent_id"})
+	grantRows := sqlmock.NewRows([]string{"role_id", "permission_id"})
+	granted := sqlmock.NewRows([]string{"id", "name"})
+	for _, role := range roles {
+		roleRows.AddRow(role.id, role.name, role.parentId)
+		for _, name := range role.permissions {
+			grantRows.AddRow(role.id, permissionIds[name])
+		}
+	}
+	for _, name := range permissions {
+		granted.AddRow(permissionIds[name], name)
+	}
+	mock.ExpectQuery("FROM `roles`").WillReturnRows(roleRows)
+	mock.ExpectQuery("FROM `role_permissions`").WillReturnRows(grantRows)
+	mock.ExpectQuery("FROM `permissions`").WillReturnRows(granted)
+}
+
+// TestPlan checks the changes Plan derives from the differences between a definition and the database
+func TestPlan(t *testing.T) {
+	viewerId := uint(1)
+	stored := []storedRole{
+		{id: 1, name: "Viewer", permissions: []string{"products:read", "orders:read"}},
+		{id: 2, name: "Editor", parentId: &viewerId, permissions: []string{"products:update"}},
+	}
+	storedPermissions := []string{"products:read", "products:update", "orders:read"}
+
+	tests := []struct {
+		name       string
+		definition Definition
+		want       []string
+	}{
+		{
+			name: "in sync",
+			definition: Definition{
+				Permissions: []string{"products:read", "products:update", "orders:read"},
+				Roles: []RoleSpec{
+					{Name: "Viewer", Permissions: []string{"products:read", "orders:read"}},
+					{Name: "Editor", Parent: "Viewer", Permissions: []string{"products:update"}},
+				},
+			},
+		},
+		{
+			name: "unlisted roles and permissions are left alone",
+			definition: Definition{
+				Roles: []RoleSpec{{Name: "Viewer", Permissions: []string{"products:read", "orders:read"}}},
+			},
+		},
+		{
+			name: "grant and revoke",
+			definition: Definition{
+				Permissions: []string{"products:read", "products:update", "products:delete"},
+				Roles: []RoleSpec{
+					{Name: "Viewer", Permissions: []string{"products:read"}},
+					{Name: "Editor", Parent: "Viewer", Permissions: []string{"products:update", "products:delete"}},
+				},
+			},
+			want: []string{
+				"+ permission products:delete",
+				"- role Viewer revoke orders:read",
+				"+ role Editor grant products:delete",
+			},
+		},
+		{
+			name: "parent changes",
+			definition: Definition{
+				Roles: []RoleSpec{
+					{Name: "Viewer", Parent: "Editor", Permissions: []string{"products:read", "orders:read"}},
+					{Name: "Editor", Permissions: []string{"products:update"}},
+				},
+			},
+			want: []string{
+				"~ role Viewer parent Editor",
+				"~ role Editor parent (none)",
+			},
+		},
+		{
+			name: "new role",
+			definition: Definition{
+				Roles: []RoleSpec{{Name: "Auditor", Parent: "Viewer", Permissions: []string{"orders:read"}}},
+			},
+			want: []string{
+				"+ role Auditor",
+				"~ role Auditor parent Viewer",
+				"+ role Auditor grant orders:read",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := mockDB(t)
+			expectStored(mock, storedPermissions, stored)
+
+			changes, err := Plan(db, &test.definition)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Fatalf("Plan() = %q, want %q", got, test.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"fmt"
+	"go-admin/middlewares"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// accessGroup registers routes that share the same access requirement
+// Every route registered through it is recorded in the middlewares route registry, so
+// missing permissions can be created at startup and Verify can detect routes that
+// were added without stating their access requirement
+type accessGroup struct {
+	router        fiber.Router
+	authenticated bool   // Whether the routes sit behind middlewares.IsAuthenticated
+	resource      string // Resource the routes belong to (empty for routes without a permission check)
+	action        string // Explicit action; empty derives the action from the HTTP method
+}
+
+// requires returns an accessGroup whose routes are guarded by middlewares.RequireAction(resource, ...)
+func requires(router fiber.Router, resource string) accessGroup {
+	return accessGroup{router: router, authenticated: true, resource: resource}
+}
+
+// With returns a copy of the group whose routes require an explicit action
+// instead of the one derived from the HTTP method (e.g., orders.With(models.ActionExport))
+func (group accessGroup) With(action string) accessGroup {
+	group.action = action
+	return group
+}
+
+// open returns an accessGroup for public routes that need neither authentication nor a permission
+func open(router fiber.Router) accessGroup {
+	return accessGroup{router: router}
+}
+
+// authenticated returns an accessGroup for self-service routes that only require a valid JWT
+func authenticated(router fiber.Router) accessGroup {
+	return accessGroup{router: router, authenticated: true}
+}
+
+// Get registers a GET route in the group
+func (group accessGroup) Get(path string, handler fiber.Handler) {
+	group.add(fiber.MethodGet, path, handler)
+}
+
+// Post registers a POST route in the group
+func (group accessGroup) Post(path string, handler fiber.Handler) {
+	group.add(fiber.MethodPost, path, handler)
+}
+
+// Put registers a PUT route in the group
+func (group accessGroup) Put(path string, handler fiber.Handler) {
+	group.add(fiber.MethodPut, path, handler)
+}
+
+// Delete registers a DELETE route in the group
+func (group accessGroup) Delete(path string, handler fiber.Handler) {
+	group.add(fiber.MethodDelete, path, handler)
+}
+
+// add registers the route, prepending the permission middleware when the group declares a resource
+func (group accessGroup) add(method string, path string, handler fiber.Handler) {
+	route := middlewares.RouteAccess{
+		Method:        method,
+		Path:          path,
+		Authenticated: group.authenticated,
+	}
+
+	if group.resource == "" {
+		middlewares.DeclareRoute(route)
+		group.router.Add([]string{method}, path, handler)
+		return
+	}
+
+	route.Resource = group.resource
+	route.Action = group.action
+	if route.Action == "" {
+		route.Action = middlewares.ActionForMethod(method)
+	}
+
+	middlewares.DeclareRoute(route)
+	group.router.Add([]string{method}, path, middlewares.RequireAction(route.Resource, route.Action), handler)
+}
+
+// Verify checks that every route registered on the app declared its access requirement
+// Returns an error naming the first route that was registered directly on the app
+// instead of through requires(...), authenticated(...) or open(...)
+// HEAD routes are checked against their GET counterpart, which Fiber registers them for
+func Verify(app *fiber.App) error {
+	for _, route := range app.GetRoutes(true) {
+		method := route.Method
+		if method == fiber.MethodHead {
+			method = fiber.MethodGet
+		}
+
+		if _, ok := middlewares.DeclaredRoute(method, route.Path); !ok {
+			return fmt.Errorf("route %s %s has no permission declared", route.Method, route.Path)
+		}
+	}
+	return nil
+}
@@ -2,65 +2,169 @@ package routes
 
 import (
 	"go-admin/controllers"
+	"go-admin/metrics"
 	"go-admin/middlewares"
+	"go-admin/models"
+	"os"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
 	"github.com/gofiber/fiber/v3/middleware/static"
 )
 
 func Setup(app *fiber.App) {
 	// Public routes - no authentication required
 	// These endpoints are accessible to unauthenticated users
-	app.Post("/api/register", controllers.Register) // Register a new user account
-	app.Post("/api/login", controllers.Login)       // Authenticate user and return JWT token
+	public := open(app)
+	public.Post("/api/register", controllers.Register) // Register a new user account
+	public.Post("/api/login", controllers.Login)       // Authenticate user and return JWT token
+
+	// Invitation acceptance - authenticated by the emailed single-use token
+	public.Post("/api/invitations/accept", controllers.AcceptInvitation) // Set password for an invited account
+
+	// Prometheus metrics - scraped with METRICS_TOKEN or read by users holding metrics:read
+	// Not served here when METRICS_ADDR moves them to a separate admin port
+	if os.Getenv("METRICS_ADDR") == "" {
+		app.Get("/metrics", middlewares.RequireMetricsAccess, adaptor.HTTPHandler(metrics.Handler()))
+		middlewares.DeclareRoute(middlewares.RouteAccess{
+			Method:   fiber.MethodGet,
+			Path:     "/metrics",
+			Resource: models.ResourceMetrics,
+			Action:   models.ActionRead,
+		})
+	}
 
 	// Apply authentication middleware to all subsequent routes
 	// All routes below this line require a valid JWT token in the request
 	app.Use(middlewares.IsAuthenticated)
 
 	// User profile management routes
-	// Users can manage their own profile information
-	app.Put("/api/users/info", controllers.UpdateInfo)         // Update current user's personal information
-	app.Put("/api/users/password", controllers.UpdatePassword) // Change current user's password
+	// Users can manage their own profile information without any permission
+	self := authenticated(app)
+	self.Put("/api/users/info", controllers.UpdateInfo)         // Update current user's personal information
+	self.Put("/api/users/password", controllers.UpdatePassword) // Change current user's password
 
 	// User session routes
-	app.Get("/api/user", controllers.User)      // Get current authenticated user's profile
-	app.Post("/api/logout", controllers.Logout) // Invalidate user session and logout
+	self.Get("/api/user", controllers.User)                        // Get current authenticated user's profile
+	self.Get("/api/user/permissions", controllers.UserPermissions) // Get current user's effective permissions
+	self.Post("/api/logout", controllers.Logout)                   // Invalidate user session and logout
+
+	// Uploaded files are displayed throughout the dashboard to any authenticated user
+	self.Get("/api/uploads*", static.New("./uploads")) // Serve uploaded files as static content
 
 	// User management routes (admin operations)
 	// Full CRUD operations for user management
-	app.Get("/api/users", controllers.AllUsers)          // Retrieve paginated list of all users
-	app.Post("/api/users", controllers.CreateUser)       // Create a new user account
-	app.Get("/api/users/:id", controllers.GetUser)       // Retrieve user details by ID
-	app.Put("/api/users/:id", controllers.UpdateUser)    // Update user information by ID
-	app.Delete("/api/users/:id", controllers.DeleteUser) // Delete a user account by ID
+	users := requires(app, models.ResourceUsers)
+	users.Get("/api/users", controllers.AllUsers)                 // Retrieve paginated list of all users
+	users.Post("/api/users", controllers.CreateUser)              // Create a new user account
+	users.Get("/api/users/:id", controllers.GetUser)              // Retrieve user details by ID
+	users.Put("/api/users/:id", controllers.UpdateUser)           // Update user information by ID
+	users.Delete("/api/users/:id", controllers.DeleteUser)        // Delete a user account by ID
+	users.Get("/api/users/:id/logins", controllers.GetUserLogins) // Retrieve login history of a user by ID
+
+	// Role assignment routes
+	// Assignments may be time-bound and are revoked automatically once they expire
+	users.Get("/api/users/:id/roles", controllers.GetUserRoles)                                        // Retrieve role assignments of a user by ID
+	users.With(models.ActionUpdate).Post("/api/users/:id/roles", controllers.GrantUserRole)            // Grant a role to a user, optionally for a limited time
+	users.With(models.ActionUpdate).Delete("/api/users/:id/roles/:roleId", controllers.RevokeUserRole) // Revoke a role from a user
+
+	// Invitation management routes
+	// Admin-created accounts receive an emailed invitation instead of a default password
+	users.Get("/api/invitations", controllers.AllInvitations)               // Retrieve paginated list of open invitations
+	users.Post("/api/invitations/:id/resend", controllers.ResendInvitation) // Issue a new invitation link and email it again
+	users.Delete("/api/invitations/:id", controllers.RevokeInvitation)      // Revoke an open invitation
 
 	// Role management routes
 	// Role-based access control (RBAC) operations
-	app.Get("/api/roles", controllers.AllRoles)          // Retrieve list of all roles
-	app.Post("/api/roles", controllers.CreateRole)       // Create a new role
-	app.Get("/api/roles/:id", controllers.GetRole)       // Retrieve role details by ID
-	app.Put("/api/roles/:id", controllers.UpdateRole)    // Update role information by ID
-	app.Delete("/api/roles/:id", controllers.DeleteRole) // Delete a role by ID
+	roles := requires(app, models.ResourceRoles)
+	roles.Get("/api/roles", controllers.AllRoles)          // Retrieve list of all roles
+	roles.Post("/api/roles", controllers.CreateRole)       // Create a new role
+	roles.Get("/api/roles/:id", controllers.GetRole)       // Retrieve role details by ID
+	roles.Put("/api/roles/:id", controllers.UpdateRole)    // Update role information by ID
+	roles.Delete("/api/roles/:id", controllers.DeleteRole) // Delete a role by ID
 
 	// Permission management routes
-	app.Get("/api/permissions", controllers.AllPermissions)    // Retrieve list of all permissions
-	app.Post("/api/permissions", controllers.CreatePermission) // Create a new permission
+	// Permissions are managed as part of role administration
+	roles.Get("/api/permissions", controllers.AllPermissions)          // Retrieve list of all permissions
+	roles.Post("/api/permissions", controllers.CreatePermission)       // Create a new permission
+	roles.Get("/api/permissions/:id", controllers.GetPermission)       // Retrieve permission details by ID
+	roles.Put("/api/permissions/:id", controllers.UpdatePermission)    // Rename a permission by ID
+	roles.Delete("/api/permissions/:id", controllers.DeletePermission) // Delete an unused permission by ID
+	roles.Get("/api/routes", controllers.AllRoutes)                    // List every route with the permission it requires
+
+	// Four-eyes approval routes
+	// Actions configured in APPROVAL_REQUIRED_ACTIONS are held as change requests until a second user approves them
+	approvals := requires(app, models.ResourceApprovals)
+	approvals.Get("/api/approvals", controllers.AllChangeRequests)                                            // Retrieve paginated list of change requests
+	approvals.Get("/api/approvals/:id", controllers.GetChangeRequest)                                         // Retrieve change request details by ID
+	approvals.With(models.ActionApprove).Post("/api/approvals/:id/approve", controllers.ApproveChangeRequest) // Approve and apply a pending change request
+	approvals.With(models.ActionApprove).Post("/api/approvals/:id/reject", controllers.RejectChangeRequest)   // Reject a pending change request
+
+	// Audit log routes
+	// Every mutating admin action is recorded together with the change it made
+	audit := requires(app, models.ResourceAudit)
+	audit.Get("/api/audit", controllers.AllAuditLogs) // Retrieve filtered, paginated audit log entries
 
 	// Product management routes
 	// Full CRUD operations for product catalog
-	app.Get("/api/products", controllers.AllProducts)          // Retrieve paginated list of products
-	app.Post("/api/products", controllers.CreateProduct)       // Create a new product
-	app.Get("/api/products/:id", controllers.GetProduct)       // Retrieve product details by ID
-	app.Put("/api/products/:id", controllers.UpdateProduct)    // Update product information by ID
-	app.Delete("/api/products/:id", controllers.DeleteProduct) // Delete a product by ID
+	products := requires(app, models.ResourceProducts)
+	products.Get("/api/products", controllers.AllProducts)          // Retrieve paginated list of products
+	products.Post("/api/products", controllers.CreateProduct)       // Create a new product
+	products.Get("/api/products/:id", controllers.GetProduct)       // Retrieve product details by ID
+	products.Put("/api/products/:id", controllers.UpdateProduct)    // Update product information by ID
+	products.Delete("/api/products/:id", controllers.DeleteProduct) // Delete a product by ID
 
-	// File upload and serving routes
-	app.Post("/api/upload", controllers.Upload)       // Upload files via multipart form data
-	app.Get("/api/uploads*", static.New("./uploads")) // Serve uploaded files as static content
+	// Inventory routes
+	// Stock levels only change through movements appended to the stock ledger
+	products.With(models.ActionUpdate).Post("/api/products/:id/stock", controllers.AdjustStock) // Record a stock movement
+	products.Get("/api/products/:id/stock", controllers.GetStockMovements)                      // Retrieve the paginated stock ledger of a product
+
+	// Variant routes
+	// Variants are nested under their product and carry their own SKU, price and stock
+	products.Get("/api/products/:id/variants", controllers.AllVariants)                                                    // Retrieve all variants of a product
+	products.Post("/api/products/:id/variants", controllers.CreateVariant)                                                 // Create a new variant
+	products.Get("/api/products/:id/variants/:variantId", controllers.GetVariant)                                          // Retrieve variant details by ID
+	products.Put("/api/products/:id/variants/:variantId", controllers.UpdateVariant)                                       // Update a variant
+	products.Delete("/api/products/:id/variants/:variantId", controllers.DeleteVariant)                                    // Delete a variant
+	products.With(models.ActionUpdate).Post("/api/products/:id/variants/:variantId/stock", controllers.AdjustVariantStock) // Record a variant stock movement
+	products.Get("/api/products/:id/variants/:variantId/stock", controllers.GetVariantStockMovements)                      // Retrieve the paginated stock ledger of a variant
+
+	// Option types (e.g., size, color) the variants of every product can use
+	products.Get("/api/option-types", controllers.AllOptionTypes)          // Retrieve all option types
+	products.Post("/api/option-types", controllers.CreateOptionType)       // Create a new option type
+	products.Put("/api/option-types/:id", controllers.UpdateOptionType)    // Rename an option type
+	products.Delete("/api/option-types/:id", controllers.DeleteOptionType) // Delete an unused option type
+
+	// Category and tag routes
+	// Catalog structure is managed with the product permissions
+	products.Get("/api/categories", controllers.AllCategories)         // Retrieve the category tree
+	products.Post("/api/categories", controllers.CreateCategory)       // Create a new category
+	products.Get("/api/categories/:id", controllers.GetCategory)       // Retrieve a category with its subtree
+	products.Put("/api/categories/:id", controllers.UpdateCategory)    // Rename or move a category
+	products.Delete("/api/categories/:id", controllers.DeleteCategory) // Delete a category without subcategories
+	products.Get("/api/tags", controllers.AllTags)                     // Retrieve all tags
+	products.Post("/api/tags", controllers.CreateTag)                  // Create a new tag
+	products.Get("/api/tags/:id", controllers.GetTag)                  // Retrieve tag details by ID
+	products.Put("/api/tags/:id", controllers.UpdateTag)               // Rename a tag
+	products.Delete("/api/tags/:id", controllers.DeleteTag)            // Delete a tag
+
+	// File upload routes
+	// Uploads are used for product images
+	products.Post("/api/upload", controllers.Upload) // Upload files via multipart form data
 
 	// Order management and analytics routes
-	app.Get("/api/orders", controllers.AllOrders) // Retrieve paginated orders with associated items
-	app.Post("/api/export", controllers.Export)   // Export orders data to CSV format
-	app.Get("/api/chart", controllers.Chart)      // Retrieve sales analytics data for chart visualization
+	orders := requires(app, models.ResourceOrders)
+	orders.Get("/api/orders", controllers.AllOrders)                         // Retrieve paginated orders with associated items
+	orders.With(models.ActionExport).Post("/api/export", controllers.Export) // Export orders data to CSV format
+	orders.Get("/api/chart", controllers.Chart)                              // Retrieve sales analytics data for chart visualization
+
+	// Landing page summary routes
+	// Users and orders are still counted under the caller's row-level policies
+	dashboard := requires(app, models.ResourceDashboard)
+	dashboard.Get("/api/dashboard", controllers.Dashboard) // Retrieve cached headline numbers and top customers
+
+	// Refuse to start if any route was registered without declaring its permission
+	if err := Verify(app); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go-admin/util"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the type of the key under which the span context of a request is stored
+// Fiber stores Locals as context values, so a fiber.Ctx passed to DB.WithContext(c)
+// carries the request span to the database spans
+type contextKey string
+
+// ContextKey holds the context carrying the current request span (see Context)
+const ContextKey contextKey = "trace_context"
+
+// Tracer creates the spans of the application
+// Spans are no-ops until Setup installs an exporting tracer provider
+var Tracer = otel.Tracer("go-admin")
+
+// Setup installs the global tracer provider and the W3C trace context propagator
+// Configuration variables:
+//   - OTEL_TRACES_EXPORTER: otlp, stdout or none (defaults to none - spans are not recorded)
+//   - OTEL_EXPORTER_OTLP_ENDPOINT: collector address for otlp (defaults to http://localhost:4318)
+//   - OTEL_SERVICE_NAME: service name reported with every span (defaults to go-admin)
+//
+// The returned function flushes pending spans and must be called before the process exits
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	// Incoming traceparent/tracestate and baggage headers are honoured even when not exporting
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := util.Getenv("OTEL_TRACES_EXPORTER", "none"); name {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		// Endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* variables
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := util.Getenv("OTEL_SERVICE_NAME", "go-admin")
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Context returns the context carrying the current request span
+// A fiber.Ctx does not carry spans itself; the tracing middleware stores the span context
+// under ContextKey, which is looked up here so spans started from c become its children
+func Context(ctx context.Context) context.Context {
+	if stored, ok := ctx.Value(ContextKey).(context.Context); ok {
+		return stored
+	}
+	return ctx
+}
+
+// Start starts a span as a child of the current request span of ctx
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer.Start(Context(ctx), name, options...)
+}
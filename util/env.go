@@ -0,0 +1,34 @@
+package util
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Getenv returns the value of an environment variable, or fallback if it is unset or empty
+// Used to make hardcoded development defaults overridable in production
+func Getenv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetenvDuration returns an environment variable parsed as a time.Duration (e.g., "72h", "15m")
+// Falls back to the provided default if the variable is unset or cannot be parsed
+func GetenvDuration(key string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return duration
+	}
+	return fallback
+}
+
+// GetenvInt returns an environment variable parsed as an integer
+// Falls back to the provided default if the variable is unset or cannot be parsed
+func GetenvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
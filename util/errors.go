@@ -0,0 +1,66 @@
+package util
+
+import "net/http"
+
+// Error is an application error that maps onto an HTTP response
+// Handlers return it instead of writing error responses themselves; the application's
+// ErrorHandler renders it as { "code", "message", "details", "request_id" }
+type Error struct {
+	Code    int         // HTTP status code (e.g., 404)
+	Message string      // Human-readable description safe to show to clients
+	Details interface{} // Optional structured information (e.g., field errors), null when absent
+}
+
+// Error implements the error interface for Error
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// NewError creates an application error with the given status code and message
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WithDetails returns a copy of the error carrying structured details
+func (err *Error) WithDetails(details interface{}) *Error {
+	copied := *err
+	copied.Details = details
+	return &copied
+}
+
+// BadRequest reports a request that cannot be processed as sent (400)
+func BadRequest(message string) *Error {
+	return NewError(http.StatusBadRequest, message)
+}
+
+// Unauthorized reports a missing or invalid authentication token (401)
+func Unauthorized() *Error {
+	return NewError(http.StatusUnauthorized, "unauthorized")
+}
+
+// Forbidden reports an authenticated caller who may not perform the request (403)
+func Forbidden(message string) *Error {
+	return NewError(http.StatusForbidden, message)
+}
+
+// NotFound reports a missing entity (404), e.g. NotFound("user not found")
+func NotFound(message string) *Error {
+	return NewError(http.StatusNotFound, message)
+}
+
+// Conflict reports a request that conflicts with the current state of an entity (409)
+func Conflict(message string) *Error {
+	return NewError(http.StatusConflict, message)
+}
+
+// Invalid reports a well-formed request whose content fails validation (422)
+// details usually maps field names to the reason they were rejected
+func Invalid(message string, details interface{}) *Error {
+	return NewError(http.StatusUnprocessableEntity, message).WithDetails(details)
+}
+
+// Internal reports an unexpected failure (500)
+// The underlying cause is logged, never sent to the client
+func Internal() *Error {
+	return NewError(http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logContextKey is the type of the keys under which request-scoped logging values are stored
+// Fiber stores Locals as context values, so handlers can pass their fiber.Ctx wherever a context is expected
+type logContextKey string
+
+const (
+	// RequestIdKey holds the correlation ID of the current request
+	RequestIdKey logContextKey = "request_id"
+
+	// LoggerKey holds the request-scoped logger, already carrying the request ID
+	LoggerKey logContextKey = "logger"
+)
+
+// sensitiveKeys lists attribute keys whose values are never written to the log
+// Keys are matched case-insensitively and by substring (e.g., "password_confirm", "X-Auth-Token")
+var sensitiveKeys = []string{"password", "token", "cookie", "jwt", "authorization", "secret"}
+
+// Logger is the process-wide structured logger
+// Configuration variables:
+//   - LOG_LEVEL: debug, info, warn or error (defaults to info)
+//   - LOG_FORMAT: json or text (defaults to json)
+var Logger = newLogger()
+
+// newLogger builds the process-wide logger from the environment
+func newLogger() *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(Getenv("LOG_LEVEL", "info"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	if Getenv("LOG_FORMAT", "json") == "text" {
+		return slog.New(slog.NewTextHandler(os.Stdout, options))
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, options))
+}
+
+// redactAttr replaces the value of sensitive attributes before they are written
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+	return attr
+}
+
+// IsSensitive reports whether a field, header or parameter name refers to a secret
+// such as a password, token or cookie
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log returns the logger of the request carried by ctx, tagged with its request ID
+// Falls back to the process-wide Logger outside of requests
+// Usage: util.Log(c).Error("failed to send invitation", "error", err)
+func Log(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(LoggerKey).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return Logger
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+)
+
+// SendMail delivers a plain text email through the SMTP server configured in the environment
+// Configuration variables:
+//   - SMTP_HOST / SMTP_PORT: mail server address (port defaults to 587)
+//   - SMTP_USERNAME / SMTP_PASSWORD: credentials for PLAIN authentication (optional)
+//   - SMTP_FROM: sender address (defaults to "no-reply@localhost")
+//
+// When SMTP_HOST is not set (typical for local development), the message is not sent;
+// only its recipient and subject are logged, since bodies carry secrets such as invitation links
+// Setting MAIL_PRINT_BODY=true additionally prints the whole message to standard output
+func SendMail(to string, subject string, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	from := Getenv("SMTP_FROM", "no-reply@localhost")
+
+	// Assemble RFC 822 message with minimal headers
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		from, to, subject, body)
+
+	// Development fallback - log the message instead of sending it
+	if host == "" {
+		Logger.Info("mail not sent, SMTP_HOST is not set", "to", to, "subject", subject)
+		if os.Getenv("MAIL_PRINT_BODY") == "true" {
+			fmt.Println(message)
+		}
+		return nil
+	}
+
+	// Authenticate only when credentials are configured
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	address := host + ":" + Getenv("SMTP_PORT", "587")
+	return smtp.SendMail(address, auth, from, []string{to}, []byte(message))
+}